@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"validators-health/internal/models"
+	"validators-health/internal/services"
+	"validators-health/internal/webhooks"
+)
+
+// apiKeyPrefix makes keys recognizable, e.g. in secret scanners.
+const apiKeyPrefix = "vh_"
+
+type APIKeysHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewAPIKeysHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *APIKeysHandler {
+	return &APIKeysHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+// CreatedAPIKey is returned once when a key is created; the key itself
+// can't be recovered later.
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+func (h *APIKeysHandler) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.CacheService.ListAPIKeys()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get API keys")
+		log.Printf("Failed to list API keys: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeysHandler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name      string             `json:"name"`
+		Scope     models.APIKeyScope `json:"scope"`
+		RateLimit int                `json:"rate_limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(request.Name) == "" {
+		writeError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	if request.Scope != models.APIKeyScopeRead && request.Scope != models.APIKeyScopeAdmin {
+		writeError(w, http.StatusBadRequest, "Scope must be 'read' or 'admin'")
+		return
+	}
+	if request.RateLimit < 0 {
+		writeError(w, http.StatusBadRequest, "Invalid 'rate_limit'")
+		return
+	}
+
+	created := CreatedAPIKey{
+		APIKey: models.APIKey{
+			ID:        webhooks.NewID(6),
+			Name:      request.Name,
+			Scope:     request.Scope,
+			RateLimit: request.RateLimit,
+			CreatedAt: time.Now(),
+		},
+		Key: apiKeyPrefix + webhooks.NewID(24),
+	}
+	if err := h.CacheService.SaveAPIKey(services.HashAPIKey(created.Key), created.APIKey); err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't save API key")
+		log.Printf("Failed to save API key: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *APIKeysHandler) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	removed, err := h.CacheService.DeleteAPIKey(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't delete API key")
+		log.Printf("Failed to delete API key %s: %v", id, err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
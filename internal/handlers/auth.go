@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"validators-health/internal/models"
+	"validators-health/internal/services"
+)
+
+// Requests authenticate with "Authorization: Bearer <key>" or an X-API-Key
+// header. Requests without a key are anonymous and get the scope set in
+// API_ANONYMOUS_SCOPE, a lower rate limit and a cap on the queried range.
+const (
+	scopeNone models.APIKeyScope = "none"
+	// scopePublic marks routes that skip authentication and rate limits.
+	scopePublic models.APIKeyScope = ""
+	// scopeAdminKey is the admin scope granted by an API key. Anonymous
+	// callers never get it, whatever API_ANONYMOUS_SCOPE says.
+	scopeAdminKey models.APIKeyScope = "admin_key"
+
+	defaultKeyRateLimit       = 600
+	defaultAnonymousRateLimit = 60
+	// defaultAnonymousRangeHours matches the longest preset of the bundled
+	// frontend, which calls the API without a key.
+	defaultAnonymousRangeHours = 30 * 24
+)
+
+type contextKey string
+
+const callerContextKey contextKey = "caller"
+
+// caller is who sent the request: an API key or, when key is nil, an
+// anonymous client.
+type caller struct {
+	key   *models.APIKey
+	scope models.APIKeyScope
+}
+
+func anonymousScope() models.APIKeyScope {
+	switch scope := models.APIKeyScope(strings.ToLower(os.Getenv("API_ANONYMOUS_SCOPE"))); scope {
+	case scopeNone, models.APIKeyScopeRead, models.APIKeyScopeAdmin:
+		return scope
+	}
+	return models.APIKeyScopeRead
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// maxQueryRange is the longest from-to range the request may ask for, or
+// zero if it is not limited.
+func maxQueryRange(r *http.Request) time.Duration {
+	c, ok := r.Context().Value(callerContextKey).(caller)
+	if !ok || c.key != nil {
+		return 0
+	}
+	return time.Duration(envInt("API_ANONYMOUS_MAX_RANGE_HOURS", defaultAnonymousRangeHours)) * time.Hour
+}
+
+func hasScope(granted, required models.APIKeyScope) bool {
+	switch required {
+	case models.APIKeyScopeRead:
+		return granted == models.APIKeyScopeRead || granted == models.APIKeyScopeAdmin
+	case models.APIKeyScopeAdmin:
+		return granted == models.APIKeyScopeAdmin
+	}
+	return false
+}
+
+func requestKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// trustedProxy reports whether ip is in API_TRUSTED_PROXIES, a
+// comma-separated list of addresses and CIDR ranges.
+func trustedProxy(ip net.IP) bool {
+	for _, entry := range strings.Split(os.Getenv("API_TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if trusted := net.ParseIP(entry); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP identifies anonymous clients. X-Real-IP, set by the ingress, is
+// only believed when the request comes from a trusted proxy; anyone else
+// could pick a fresh rate limit bucket with it.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		if remote := net.ParseIP(host); remote != nil && trustedProxy(remote) {
+			return ip
+		}
+	}
+	return host
+}
+
+// identify resolves the request's API key. API_ADMIN_KEY is accepted as an
+// admin key so the first keys can be created through the API.
+func (h *Handlers) identify(r *http.Request) (*caller, error) {
+	secret := requestKey(r)
+	if secret == "" {
+		return &caller{scope: anonymousScope()}, nil
+	}
+
+	if adminKey := os.Getenv("API_ADMIN_KEY"); adminKey != "" &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(adminKey)) == 1 {
+		return &caller{key: &models.APIKey{ID: "env", Name: "API_ADMIN_KEY", Scope: models.APIKeyScopeAdmin}, scope: models.APIKeyScopeAdmin}, nil
+	}
+
+	hash := services.HashAPIKey(secret)
+	key, err := h.CacheService.GetAPIKey(hash)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	return &caller{key: key, scope: key.Scope}, nil
+}
+
+// authorize checks the caller's scope and rate limit before passing the
+// request on.
+func (h *Handlers) authorize(required models.APIKeyScope, next http.HandlerFunc) http.HandlerFunc {
+	if required == scopePublic {
+		return next
+	}
+
+	keyRequired := required == scopeAdminKey
+	if keyRequired {
+		required = models.APIKeyScopeAdmin
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := h.identify(r)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Couldn't check API key")
+			log.Printf("Failed to look up API key: %v", err)
+			return
+		}
+		if c == nil {
+			writeError(w, http.StatusUnauthorized, "Invalid API key")
+			return
+		}
+		if !hasScope(c.scope, required) || (keyRequired && c.key == nil) {
+			if c.key == nil {
+				writeError(w, http.StatusUnauthorized, "An API key is required")
+			} else {
+				writeError(w, http.StatusForbidden, fmt.Sprintf("The API key lacks the %s scope", required))
+			}
+			return
+		}
+
+		bucket := "anonymous:" + clientIP(r)
+		limit := envInt("API_ANONYMOUS_RATE_LIMIT", defaultAnonymousRateLimit)
+		if c.key != nil {
+			bucket = "key:" + c.key.ID
+			limit = c.key.RateLimit
+			if limit <= 0 {
+				limit = envInt("API_KEY_RATE_LIMIT", defaultKeyRateLimit)
+			}
+		}
+		allowed, wait, err := h.CacheService.TakeToken(bucket, limit)
+		if err != nil {
+			// Don't turn a Redis hiccup into an outage.
+			log.Printf("Failed to check rate limit of %s: %v", bucket, err)
+			allowed = true
+		}
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), callerContextKey, *c)))
+	}
+}
@@ -29,30 +29,10 @@ type ChartResponse struct {
 
 func (h *ChartHandler) GetChartData(w http.ResponseWriter, r *http.Request) {
 	adnls := r.URL.Query()["adnl"]
-	from := r.URL.Query().Get("from")
-	to := r.URL.Query().Get("to")
-
-	var fromTime, toTime time.Time
-	if from == "" || to == "" {
-		toTime = time.Now()
-		fromTime = toTime.Add(-24 * time.Hour)
-	} else {
-
-		fromTimestamp, err := strconv.ParseInt(from, 10, 64)
-		if err != nil {
-			http.Error(w, "Invalid 'from' timestamp", http.StatusBadRequest)
-			log.Printf("Invalid 'from' timestamp: %v", err)
-			return
-		}
-		toTimestamp, err := strconv.ParseInt(to, 10, 64)
-		if err != nil {
-			http.Error(w, "Invalid 'to' timestamp", http.StatusBadRequest)
-			log.Printf("Invalid 'to' timestamp: %v", err)
-			return
-		}
-
-		fromTime = time.Unix(fromTimestamp, 0)
-		toTime = time.Unix(toTimestamp, 0)
+	fromTime, toTime, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	log.Printf("Handling request with ADNLs: %v, from: %d, to: %d", adnls, fromTime.Unix(), toTime.Unix())
@@ -65,7 +45,7 @@ func (h *ChartHandler) GetChartData(w http.ResponseWriter, r *http.Request) {
 
 		found, err := h.CacheService.GetCachedData(cacheKey, &cachedData)
 		if err != nil {
-			http.Error(w, "Failed to access cache", http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, "Failed to access cache")
 			log.Printf("Failed to access cache for key %s: %v", cacheKey, err)
 			return
 		}
@@ -89,7 +69,7 @@ func (h *ChartHandler) GetChartData(w http.ResponseWriter, r *http.Request) {
 
 		data, err := h.ClickhouseService.GetEfficiencyChartDataCached(adnl, fromTime, toTime, h.CacheService)
 		if err != nil {
-			http.Error(w, "Failed to query ClickHouse", http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, "Failed to query ClickHouse")
 			log.Printf("Failed to query ClickHouse for ADNL %s: %v", adnl, err)
 			return
 		}
@@ -105,7 +85,7 @@ func (h *ChartHandler) GetChartData(w http.ResponseWriter, r *http.Request) {
 
 		err = h.CacheService.CacheData(cacheKey, data, time.Hour)
 		if err != nil {
-			http.Error(w, "Failed to cache data", http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, "Failed to cache data")
 			log.Printf("Failed to cache data for key %s: %v", cacheKey, err)
 			return
 		}
@@ -118,7 +98,6 @@ func (h *ChartHandler) GetChartData(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(results); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		log.Printf("Failed to encode response: %v", err)
 	}
 }
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"validators-health/internal/services"
+)
+
+const maxComparedValidators = 20
+
+type CompareHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewCompareHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *CompareHandler {
+	return &CompareHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+// CompareValidatorsHandler compares the validators in the comma-separated
+// 'adnl' parameter with the rest of the network over the range.
+func (h *CompareHandler) CompareValidatorsHandler(w http.ResponseWriter, r *http.Request) {
+	var adnls []string
+	seen := make(map[string]bool)
+	for _, adnl := range strings.Split(r.URL.Query().Get("adnl"), ",") {
+		adnl = strings.ToUpper(strings.TrimSpace(adnl))
+		if adnl == "" || seen[adnl] {
+			continue
+		}
+		if !adnlPattern.MatchString(adnl) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ADNL address %q", adnl))
+			return
+		}
+		seen[adnl] = true
+		adnls = append(adnls, adnl)
+	}
+	if len(adnls) == 0 || len(adnls) > maxComparedValidators {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Required param 'adnl': 1 to %d comma-separated addresses", maxComparedValidators))
+		return
+	}
+
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comparison, err := h.ClickhouseService.CompareValidators(adnls, from, to, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't compare validators")
+		log.Printf("Failed to compare validators %v: %v", adnls, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, comparison)
+}
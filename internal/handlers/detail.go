@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"validators-health/internal/models"
+	"validators-health/internal/services"
+)
+
+const (
+	defaultDetailCycles   = 20
+	maxDetailCycles       = 200
+	defaultDetailHistory  = 50
+	detailComplaintsLimit = 50
+)
+
+type ValidatorDetailHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewValidatorDetailHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *ValidatorDetailHandler {
+	return &ValidatorDetailHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+// parseLimit reads an optional positive integer parameter of at most max.
+func parseLimit(r *http.Request, name string, fallback, max int) (int, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > max {
+		return 0, false
+	}
+	return limit, true
+}
+
+// ValidatorHandler returns everything known about one validator: its
+// current status, election entries and efficiency per cycle, status
+// history, complaints and how many chats follow it.
+func (h *ValidatorDetailHandler) ValidatorHandler(w http.ResponseWriter, r *http.Request) {
+	adnl := r.PathValue("adnl")
+	if !adnlPattern.MatchString(adnl) {
+		writeError(w, http.StatusBadRequest, "Invalid ADNL address")
+		return
+	}
+	cycles, ok := parseLimit(r, "cycles", defaultDetailCycles, maxDetailCycles)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Invalid 'cycles'")
+		return
+	}
+	historyLimit, ok := parseLimit(r, "history", defaultDetailHistory, 1000)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Invalid 'history'")
+		return
+	}
+
+	info, err := h.ClickhouseService.GetValidatorInfo(adnl, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get validator")
+		log.Printf("Failed to get info of validator %s: %v", adnl, err)
+		return
+	}
+	current, err := h.ClickhouseService.GetCurrentStatus(adnl, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get validator")
+		log.Printf("Failed to get status of validator %s: %v", adnl, err)
+		return
+	}
+	if info == nil && current == nil {
+		writeError(w, http.StatusNotFound, "Validator not found")
+		return
+	}
+
+	detail := models.ValidatorDetail{ADNLAddr: adnl}
+	if info != nil {
+		detail.PubKey = info.PubKey
+		detail.WalletAddress = info.WalletAddress
+	}
+	if current != nil {
+		detail.Status = models.ValidatorStatus(current.Status)
+		detail.StatusSince = &current.Timestamp
+	}
+
+	detail.Cycles, err = h.ClickhouseService.GetValidatorCycles(adnl, cycles, efficiencyThreshold(), h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get validator cycles")
+		log.Printf("Failed to get cycles of validator %s: %v", adnl, err)
+		return
+	}
+
+	history, err := h.ClickhouseService.GetStatusHistory(adnl, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get status history")
+		log.Printf("Failed to get status history of validator %s: %v", adnl, err)
+		return
+	}
+	if len(history) > historyLimit {
+		history = history[:historyLimit]
+	}
+	detail.StatusHistory = history
+	if detail.StatusHistory == nil {
+		detail.StatusHistory = []models.ValidatorStatusHistory{}
+	}
+
+	detail.Complaints, err = h.ClickhouseService.GetValidatorComplaints(adnl, detailComplaintsLimit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get complaints")
+		log.Printf("Failed to get complaints of validator %s: %v", adnl, err)
+		return
+	}
+
+	groups, err := h.ClickhouseService.GetGroupsOf(adnl, h.CacheService)
+	if err != nil {
+		log.Printf("Failed to get groups of validator %s: %v", adnl, err)
+	}
+	// Subscriptions are keyed by the scoreboard ADNL, which may differ.
+	adnls := []string{adnl}
+	validatorADNLs, err := h.ClickhouseService.GetValidatorADNLs(adnls)
+	if err != nil {
+		log.Printf("Failed to resolve validator ADNL of %s: %v", adnl, err)
+	} else if validatorADNL := validatorADNLs[adnl]; validatorADNL != "" && validatorADNL != adnl {
+		adnls = append(adnls, validatorADNL)
+	}
+	detail.Subscribers, err = h.CacheService.CountSubscribers(adnls, groups)
+	if err != nil {
+		log.Printf("Failed to count subscribers of validator %s: %v", adnl, err)
+	}
+
+	writeJSON(w, http.StatusOK, detail)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"validators-health/internal/models"
+	"validators-health/internal/services"
+)
+
+var (
+	adnlPattern      = regexp.MustCompile(`^[A-F0-9]{64}$`)
+	groupNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+)
+
+type GroupsHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewGroupsHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *GroupsHandler {
+	return &GroupsHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+type GroupResponse struct {
+	Group   models.GroupStats         `json:"group"`
+	Members []models.ValidatorSummary `json:"members"`
+}
+
+func (h *GroupsHandler) GroupsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := h.ClickhouseService.GetGroupsStats(from, to, efficiencyThreshold(), h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get groups")
+		log.Printf("Failed to get groups stats: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+func (h *GroupsHandler) GroupHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	group, err := h.ClickhouseService.ResolveGroup(r.PathValue("name"), h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get group")
+		log.Printf("Failed to resolve group %s: %v", r.PathValue("name"), err)
+		return
+	}
+	if group == nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	summaries, err := h.ClickhouseService.GetValidatorsSummary(group.Members, from, to, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get group")
+		log.Printf("Failed to get summary for group %s: %v", group.Name, err)
+		return
+	}
+
+	response := GroupResponse{
+		Group:   services.AggregateGroup(*group, summaries, efficiencyThreshold()),
+		Members: make([]models.ValidatorSummary, 0, len(group.Members)),
+	}
+	for _, member := range group.Members {
+		summary, ok := summaries[member]
+		if !ok {
+			summary = models.ValidatorSummary{ADNLAddr: member}
+		}
+		response.Members = append(response.Members, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+func (h *GroupsHandler) SaveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !groupNamePattern.MatchString(name) {
+		writeError(w, http.StatusBadRequest, "Invalid group name")
+		return
+	}
+
+	var request struct {
+		Members []string `json:"members"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	for _, member := range request.Members {
+		if !adnlPattern.MatchString(member) {
+			writeError(w, http.StatusBadRequest, "Invalid ADNL: "+member)
+			return
+		}
+	}
+
+	if err := h.CacheService.SaveGroup(name, request.Members); err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't save group")
+		log.Printf("Failed to save group %s: %v", name, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *GroupsHandler) DeleteGroupHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := h.CacheService.DeleteGroup(name); err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't delete group")
+		log.Printf("Failed to delete group %s: %v", name, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
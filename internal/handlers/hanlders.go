@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 	"validators-health/internal/services"
 )
 
@@ -32,3 +36,154 @@ func (h *Handlers) ValidatorStatusesHandler(w http.ResponseWriter, r *http.Reque
 	validatorsHandler := NewValidatorsHandler(h.ClickhouseService, h.CacheService)
 	validatorsHandler.ValidatorStatusesHandler(w, r)
 }
+
+func (h *Handlers) CycleReportHandler(w http.ResponseWriter, r *http.Request) {
+	reportHandler := NewReportHandler(h.ClickhouseService, h.CacheService)
+	reportHandler.CycleReportHandler(w, r)
+}
+
+func (h *Handlers) ValidatorSLAHandler(w http.ResponseWriter, r *http.Request) {
+	slaHandler := NewSLAHandler(h.ClickhouseService, h.CacheService)
+	slaHandler.ValidatorSLAHandler(w, r)
+}
+
+func (h *Handlers) ListValidatorsHandler(w http.ResponseWriter, r *http.Request) {
+	validatorsHandler := NewValidatorsHandler(h.ClickhouseService, h.CacheService)
+	validatorsHandler.ListValidatorsHandler(w, r)
+}
+
+func (h *Handlers) CompareValidatorsHandler(w http.ResponseWriter, r *http.Request) {
+	compareHandler := NewCompareHandler(h.ClickhouseService, h.CacheService)
+	compareHandler.CompareValidatorsHandler(w, r)
+}
+
+func (h *Handlers) ValidatorHandler(w http.ResponseWriter, r *http.Request) {
+	detailHandler := NewValidatorDetailHandler(h.ClickhouseService, h.CacheService)
+	detailHandler.ValidatorHandler(w, r)
+}
+
+func (h *Handlers) StakeHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	stakeHandler := NewStakeHandler(h.ClickhouseService, h.CacheService)
+	stakeHandler.StakeHistoryHandler(w, r)
+}
+
+func (h *Handlers) GroupsHandler(w http.ResponseWriter, r *http.Request) {
+	groupsHandler := NewGroupsHandler(h.ClickhouseService, h.CacheService)
+	groupsHandler.GroupsHandler(w, r)
+}
+
+func (h *Handlers) GroupHandler(w http.ResponseWriter, r *http.Request) {
+	groupsHandler := NewGroupsHandler(h.ClickhouseService, h.CacheService)
+	groupsHandler.GroupHandler(w, r)
+}
+
+func (h *Handlers) SaveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	groupsHandler := NewGroupsHandler(h.ClickhouseService, h.CacheService)
+	groupsHandler.SaveGroupHandler(w, r)
+}
+
+func (h *Handlers) DeleteGroupHandler(w http.ResponseWriter, r *http.Request) {
+	groupsHandler := NewGroupsHandler(h.ClickhouseService, h.CacheService)
+	groupsHandler.DeleteGroupHandler(w, r)
+}
+
+func (h *Handlers) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	streamHandler := NewStreamHandler(h.ClickhouseService, h.CacheService)
+	streamHandler.StreamHandler(w, r)
+}
+
+func (h *Handlers) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooksHandler := NewWebhooksHandler(h.ClickhouseService, h.CacheService)
+	webhooksHandler.ListWebhooksHandler(w, r)
+}
+
+func (h *Handlers) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhooksHandler := NewWebhooksHandler(h.ClickhouseService, h.CacheService)
+	webhooksHandler.GetWebhookHandler(w, r)
+}
+
+func (h *Handlers) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhooksHandler := NewWebhooksHandler(h.ClickhouseService, h.CacheService)
+	webhooksHandler.CreateWebhookHandler(w, r)
+}
+
+func (h *Handlers) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhooksHandler := NewWebhooksHandler(h.ClickhouseService, h.CacheService)
+	webhooksHandler.DeleteWebhookHandler(w, r)
+}
+
+func (h *Handlers) WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	webhooksHandler := NewWebhooksHandler(h.ClickhouseService, h.CacheService)
+	webhooksHandler.WebhookDeliveriesHandler(w, r)
+}
+
+func (h *Handlers) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	apiKeysHandler := NewAPIKeysHandler(h.ClickhouseService, h.CacheService)
+	apiKeysHandler.ListAPIKeysHandler(w, r)
+}
+
+func (h *Handlers) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	apiKeysHandler := NewAPIKeysHandler(h.ClickhouseService, h.CacheService)
+	apiKeysHandler.CreateAPIKeyHandler(w, r)
+}
+
+func (h *Handlers) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	apiKeysHandler := NewAPIKeysHandler(h.ClickhouseService, h.CacheService)
+	apiKeysHandler.DeleteAPIKeyHandler(w, r)
+}
+
+// parseTimeRange reads the optional 'from' and 'to' unix timestamps,
+// defaulting to the last 24 hours when both are absent, and enforces the
+// caller's range limit.
+func parseTimeRange(r *http.Request) (time.Time, time.Time, error) {
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if (from == "") != (to == "") {
+		return time.Time{}, time.Time{}, fmt.Errorf("'from' and 'to' must be given together")
+	}
+	if from == "" {
+		toTime := time.Now()
+		fromTime := toTime.Add(-24 * time.Hour)
+		if limit := maxQueryRange(r); limit > 0 && limit < 24*time.Hour {
+			fromTime = toTime.Add(-limit)
+		}
+		return fromTime, toTime, nil
+	}
+
+	fromTimestamp, err := strconv.ParseInt(from, 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Invalid 'from' timestamp")
+	}
+	toTimestamp, err := strconv.ParseInt(to, 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Invalid 'to' timestamp")
+	}
+
+	fromTime := time.Unix(fromTimestamp, 0)
+	toTime := time.Unix(toTimestamp, 0)
+	if !toTime.After(fromTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("'to' must be after 'from'")
+	}
+	if limit := maxQueryRange(r); limit > 0 && toTime.Sub(fromTime) > limit {
+		return time.Time{}, time.Time{}, fmt.Errorf("The range may not exceed %d hours without an API key", int(limit.Hours()))
+	}
+	return fromTime, toTime, nil
+}
+
+func efficiencyThreshold() float64 {
+	threshold, _ := strconv.ParseFloat(os.Getenv("EFFICIENCY_THRESHOLD"), 64)
+	return threshold
+}
+
+// parseCycleID reads the optional 'cycle_id' parameter; zero means any cycle.
+func parseCycleID(r *http.Request) (uint32, error) {
+	value := r.URL.Query().Get("cycle_id")
+	if value == "" {
+		return 0, nil
+	}
+	cycleID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid 'cycle_id'")
+	}
+	return uint32(cycleID), nil
+}
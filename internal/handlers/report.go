@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"validators-health/internal/models"
+	"validators-health/internal/services"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type ReportHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewReportHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *ReportHandler {
+	return &ReportHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+var cycleReportCSVHeader = []string{
+	"adnl_addr",
+	"wallet_address",
+	"index",
+	"samples",
+	"no_data",
+	"avg_efficiency",
+	"min_efficiency",
+	"p5_efficiency",
+	"seconds_below_threshold",
+	"status_flips",
+	"stake",
+	"weight",
+	"complaints",
+	"expected_reward_share",
+	"effective_reward_share",
+	"reward_share_loss",
+	"estimated_exposure_ton",
+}
+
+func (h *ReportHandler) CycleReportHandler(w http.ResponseWriter, r *http.Request) {
+	cycleID, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid cycle id")
+		return
+	}
+
+	threshold := efficiencyThreshold()
+	if thresholdStr := r.URL.Query().Get("threshold"); thresholdStr != "" {
+		threshold, err = strconv.ParseFloat(thresholdStr, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid param 'threshold'")
+			return
+		}
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "parquet" {
+		writeError(w, http.StatusBadRequest, "Invalid param 'format', expected one of: json, csv, parquet")
+		return
+	}
+
+	report, err := h.ClickhouseService.GetCycleReport(uint32(cycleID), threshold, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't build cycle report")
+		log.Printf("Failed to build report for cycle %d: %v", cycleID, err)
+		return
+	}
+	if report == nil {
+		writeError(w, http.StatusNotFound, "Cycle not found")
+		return
+	}
+
+	filename := fmt.Sprintf("cycle-%d-report.%s", cycleID, format)
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		err = writeCycleReportCSV(w, report.Validators)
+	case "parquet":
+		w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		err = parquet.Write(w, report.Validators)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(report)
+	}
+	if err != nil {
+		log.Printf("Failed to write %s report for cycle %d: %v", format, cycleID, err)
+	}
+}
+
+func writeCycleReportCSV(w http.ResponseWriter, rows []models.ValidatorCycleReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(cycleReportCSVHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := []string{
+			row.ADNLAddr,
+			row.WalletAddress,
+			strconv.Itoa(int(row.Index)),
+			strconv.FormatUint(row.Samples, 10),
+			strconv.FormatBool(row.NoData),
+			strconv.FormatFloat(row.AvgEfficiency, 'f', -1, 64),
+			strconv.FormatFloat(row.MinEfficiency, 'f', -1, 64),
+			strconv.FormatFloat(row.P5Efficiency, 'f', -1, 64),
+			strconv.FormatInt(row.SecondsBelowThreshold, 10),
+			strconv.FormatUint(row.StatusFlips, 10),
+			strconv.FormatInt(row.Stake, 10),
+			strconv.FormatInt(row.Weight, 10),
+			strconv.FormatUint(row.Complaints, 10),
+			strconv.FormatFloat(row.ExpectedRewardShare, 'f', -1, 64),
+			strconv.FormatFloat(row.EffectiveRewardShare, 'f', -1, 64),
+			strconv.FormatFloat(row.RewardShareLoss, 'f', -1, 64),
+			strconv.FormatFloat(row.EstimatedExposureTON, 'f', 2, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"validators-health/internal/models"
+)
+
+const (
+	APIPrefix = "/api/v1"
+	// legacyAPIPrefix serves the same routes for clients written before the
+	// API was versioned.
+	legacyAPIPrefix = "/api"
+)
+
+// legacyAnyMethod lists the routes that accepted any method before the API
+// was versioned. Their unversioned aliases still do.
+var legacyAnyMethod = map[string]bool{
+	"/chart":              true,
+	"/health":             true,
+	"/validator-statuses": true,
+}
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// ErrorResponse is the body of every API error.
+type ErrorResponse struct {
+	Error ErrorDetails `json:"error"`
+}
+
+type ErrorDetails struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: ErrorDetails{Status: status, Message: message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+type route struct {
+	method  string
+	path    string
+	scope   models.APIKeyScope
+	handler http.HandlerFunc
+}
+
+// routes lists the API endpoints relative to the API prefix. Keep
+// openapi.json in sync when changing them.
+func (h *Handlers) routes() []route {
+	return []route{
+		{"GET", "/health", scopePublic, h.HealthHandler},
+		{"GET", "/chart", models.APIKeyScopeRead, h.ChartHandler},
+		{"GET", "/validator-statuses", models.APIKeyScopeRead, h.ValidatorStatusesHandler},
+		{"GET", "/cycles/{id}/report", models.APIKeyScopeRead, h.CycleReportHandler},
+		{"GET", "/validators", models.APIKeyScopeRead, h.ListValidatorsHandler},
+		{"GET", "/validators/compare", models.APIKeyScopeRead, h.CompareValidatorsHandler},
+		{"GET", "/validators/{adnl}", models.APIKeyScopeRead, h.ValidatorHandler},
+		{"GET", "/validators/{adnl}/sla", models.APIKeyScopeRead, h.ValidatorSLAHandler},
+		{"GET", "/validators/{adnl}/stake-history", models.APIKeyScopeRead, h.StakeHistoryHandler},
+		{"GET", "/groups", models.APIKeyScopeRead, h.GroupsHandler},
+		{"GET", "/groups/{name}", models.APIKeyScopeRead, h.GroupHandler},
+		{"PUT", "/groups/{name}", scopeAdminKey, h.SaveGroupHandler},
+		{"DELETE", "/groups/{name}", scopeAdminKey, h.DeleteGroupHandler},
+		{"GET", "/stream", models.APIKeyScopeRead, h.StreamHandler},
+		{"GET", "/webhooks", scopeAdminKey, h.ListWebhooksHandler},
+		{"POST", "/webhooks", scopeAdminKey, h.CreateWebhookHandler},
+		{"GET", "/webhooks/{id}", scopeAdminKey, h.GetWebhookHandler},
+		{"DELETE", "/webhooks/{id}", scopeAdminKey, h.DeleteWebhookHandler},
+		{"GET", "/webhooks/{id}/deliveries", scopeAdminKey, h.WebhookDeliveriesHandler},
+		{"GET", "/keys", scopeAdminKey, h.ListAPIKeysHandler},
+		{"POST", "/keys", scopeAdminKey, h.CreateAPIKeyHandler},
+		{"DELETE", "/keys/{id}", scopeAdminKey, h.DeleteAPIKeyHandler},
+	}
+}
+
+// Register adds the API under /api/v1, its unversioned aliases under /api
+// and the OpenAPI specification to the mux. Each route requires its scope.
+func (h *Handlers) Register(mux *http.ServeMux) {
+	for _, route := range h.routes() {
+		handler := h.authorize(route.scope, route.handler)
+		mux.HandleFunc(route.method+" "+APIPrefix+route.path, handler)
+		if legacyAnyMethod[route.path] {
+			mux.HandleFunc(legacyAPIPrefix+route.path, handler)
+		} else {
+			mux.HandleFunc(route.method+" "+legacyAPIPrefix+route.path, handler)
+		}
+	}
+	mux.HandleFunc("GET "+APIPrefix+"/openapi.json", h.OpenAPIHandler)
+	mux.HandleFunc(APIPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "Not found")
+	})
+}
+
+// JSONErrors wraps the mux so the plain-text errors it writes itself, such
+// as 405 for a method a route doesn't allow, use the API error format.
+func JSONErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, legacyAPIPrefix+"/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&errorWriter{ResponseWriter: w}, r)
+	})
+}
+
+// errorWriter replaces plain-text error responses with ErrorResponse.
+type errorWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
+
+func (w *errorWriter) WriteHeader(status int) {
+	if status >= 400 && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		w.replaced = true
+		writeError(w.ResponseWriter, status, http.StatusText(status))
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming working through the wrapper.
+func (w *errorWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *errorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (h *Handlers) OpenAPIHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"validators-health/internal/services"
+)
+
+type SLAHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewSLAHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *SLAHandler {
+	return &SLAHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+func (h *SLAHandler) ValidatorSLAHandler(w http.ResponseWriter, r *http.Request) {
+	adnl := r.PathValue("adnl")
+	fromTime, toTime, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sla, err := h.ClickhouseService.GetValidatorSLA(adnl, fromTime, toTime, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't compute SLA")
+		log.Printf("Failed to compute SLA for ADNL %s: %v", adnl, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sla); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
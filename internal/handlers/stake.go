@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"validators-health/internal/models"
+	"validators-health/internal/services"
+)
+
+const (
+	defaultStakeHistoryCycles = 50
+	maxStakeHistoryCycles     = 500
+)
+
+type StakeHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewStakeHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *StakeHandler {
+	return &StakeHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+// StakeHistoryHandler returns the validator's stake, weight and max factor
+// in each of the last 'cycles' cycles, marking the changes that are alerted.
+func (h *StakeHandler) StakeHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	adnl := r.PathValue("adnl")
+	if !adnlPattern.MatchString(adnl) {
+		writeError(w, http.StatusBadRequest, "Invalid ADNL address")
+		return
+	}
+	cycles, ok := parseLimit(r, "cycles", defaultStakeHistoryCycles, maxStakeHistoryCycles)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Invalid 'cycles'")
+		return
+	}
+
+	history, err := h.ClickhouseService.GetStakeHistory(adnl, cycles, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get stake history")
+		log.Printf("Failed to get stake history of validator %s: %v", adnl, err)
+		return
+	}
+	participated := false
+	for _, entry := range history {
+		participated = participated || entry.Participated
+	}
+	if !participated {
+		writeError(w, http.StatusNotFound, "Validator not found")
+		return
+	}
+
+	config := services.GetStakeAlertConfig()
+	writeJSON(w, http.StatusOK, struct {
+		ADNLAddr           string                     `json:"adnl_addr"`
+		ChangeThreshold    float64                    `json:"change_threshold_percent"`
+		MissedCyclesToLeft int                        `json:"missed_cycles_to_leave"`
+		Cycles             []models.StakeHistoryEntry `json:"cycles"`
+	}{
+		ADNLAddr:           adnl,
+		ChangeThreshold:    config.ChangePercent,
+		MissedCyclesToLeft: config.MissedCycles,
+		Cycles:             history,
+	})
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"validators-health/internal/models"
+	"validators-health/internal/services"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment so proxies
+// don't close the connection.
+const streamKeepAlive = 15 * time.Second
+
+var eventIDPattern = regexp.MustCompile(`^\d+-\d+$`)
+
+type StreamHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewStreamHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *StreamHandler {
+	return &StreamHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+// streamFilter selects the events a client asked for; empty fields match
+// everything.
+type streamFilter struct {
+	adnls   map[string]bool
+	cycleID uint32
+}
+
+func (f streamFilter) matches(event models.StreamEvent) bool {
+	if f.cycleID != 0 && event.CycleID != f.cycleID {
+		return false
+	}
+	if f.adnls != nil && !f.adnls[event.ADNLAddr] && !f.adnls[event.ValidatorADNL] {
+		return false
+	}
+	return true
+}
+
+func (h *StreamHandler) parseFilter(r *http.Request) (streamFilter, int, error) {
+	var filter streamFilter
+	query := r.URL.Query()
+
+	if adnls := query.Get("adnl"); adnls != "" {
+		filter.adnls = make(map[string]bool)
+		for _, adnl := range strings.Split(adnls, ",") {
+			filter.adnls[strings.TrimSpace(adnl)] = true
+		}
+	}
+
+	if wallet := query.Get("wallet"); wallet != "" {
+		group, err := h.ClickhouseService.GetWalletGroup(wallet, h.CacheService)
+		if err != nil {
+			log.Printf("Failed to get validators of wallet %s: %v", wallet, err)
+			return filter, http.StatusInternalServerError, fmt.Errorf("couldn't resolve wallet")
+		}
+		if group == nil {
+			return filter, http.StatusNotFound, fmt.Errorf("no validators for wallet %s", wallet)
+		}
+		walletADNLs := make(map[string]bool, len(group.Members))
+		for _, adnl := range group.Members {
+			if filter.adnls == nil || filter.adnls[adnl] {
+				walletADNLs[adnl] = true
+			}
+		}
+		filter.adnls = walletADNLs
+	}
+
+	cycleID, err := parseCycleID(r)
+	if err != nil {
+		return filter, http.StatusBadRequest, err
+	}
+	filter.cycleID = cycleID
+
+	return filter, 0, nil
+}
+
+// StreamHandler sends scoreboard samples and status transitions as
+// Server-Sent Events. Clients resume after a reconnect with the
+// Last-Event-ID header or the last_event_id parameter.
+func (h *StreamHandler) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	filter, status, err := h.parseFilter(r)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	if lastID != "" && !eventIDPattern.MatchString(lastID) {
+		writeError(w, http.StatusBadRequest, "Invalid last event ID")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+
+	send := func(event models.StreamEvent) {
+		if lastID != "" && compareEventIDs(event.ID, lastID) <= 0 {
+			return
+		}
+		lastID = event.ID
+		if !filter.matches(event) {
+			return
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	}
+
+	catchUp := func() bool {
+		for {
+			events, err := h.CacheService.ReadEvents(r.Context(), lastID, -1)
+			if err != nil {
+				log.Printf("Failed to read missed events: %v", err)
+				return false
+			}
+			if len(events) == 0 {
+				return true
+			}
+			for _, event := range events {
+				send(event)
+			}
+			flusher.Flush()
+		}
+	}
+
+	// The missed events are written straight to the client, which may take
+	// longer than the live buffer lasts. Subscribing afterwards leaves a
+	// short gap, which the second catch-up closes; events seen twice are
+	// skipped by their ID.
+	if lastID != "" && !catchUp() {
+		return
+	}
+	hub := getEventHub(h.CacheService)
+	live := hub.subscribe(filter)
+	defer hub.unsubscribe(live)
+	if lastID != "" && !catchUp() {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-live:
+			if !ok {
+				// The client fell too far behind; it resumes after reconnecting.
+				return
+			}
+			send(event)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// compareEventIDs orders Redis stream IDs of the form "<ms>-<seq>".
+func compareEventIDs(a, b string) int {
+	aMs, aSeq, _ := strings.Cut(a, "-")
+	bMs, bSeq, _ := strings.Cut(b, "-")
+	for _, pair := range [][2]string{{aMs, bMs}, {aSeq, bSeq}} {
+		x, _ := strconv.ParseUint(pair[0], 10, 64)
+		y, _ := strconv.ParseUint(pair[1], 10, 64)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// eventHub reads the event stream once per process and fans it out to the
+// connected clients, so each client doesn't hold a blocked Redis connection.
+// Each client only gets the events matching its filter.
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan models.StreamEvent]streamFilter
+}
+
+const subscriberBuffer = 1000
+
+var (
+	eventHubOnce     sync.Once
+	eventHubInstance *eventHub
+)
+
+func getEventHub(cacheService *services.CacheService) *eventHub {
+	eventHubOnce.Do(func() {
+		eventHubInstance = &eventHub{subscribers: make(map[chan models.StreamEvent]streamFilter)}
+		go eventHubInstance.run(cacheService)
+	})
+	return eventHubInstance
+}
+
+func (h *eventHub) subscribe(filter streamFilter) chan models.StreamEvent {
+	ch := make(chan models.StreamEvent, subscriberBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = filter
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan models.StreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (h *eventHub) run(cacheService *services.CacheService) {
+	ctx := context.Background()
+	lastID := ""
+	for {
+		if lastID == "" {
+			id, err := cacheService.LastEventID(ctx)
+			if err != nil {
+				log.Printf("Failed to get last event ID: %v", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			lastID = id
+		}
+
+		events, err := cacheService.ReadEvents(ctx, lastID, streamKeepAlive)
+		if err != nil {
+			log.Printf("Failed to read events: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if len(events) == 0 {
+			continue
+		}
+		lastID = events[len(events)-1].ID
+
+		h.mu.Lock()
+		for ch, filter := range h.subscribers {
+			var matching []models.StreamEvent
+			for _, event := range events {
+				if filter.matches(event) {
+					matching = append(matching, event)
+				}
+			}
+			if len(matching) > cap(ch)-len(ch) {
+				// Drop clients that fall behind instead of blocking the others.
+				delete(h.subscribers, ch)
+				close(ch)
+				continue
+			}
+			for _, event := range matching {
+				ch <- event
+			}
+		}
+		h.mu.Unlock()
+	}
+}
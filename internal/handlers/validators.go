@@ -1,15 +1,116 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
-	"time"
+	"strings"
 	"validators-health/internal/models"
 	"validators-health/internal/services"
 )
 
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// listSortKeys maps the 'sort' parameter to the sorted value and its default
+// order.
+var listSortKeys = map[string]struct {
+	value      func(models.ValidatorListItem) float64
+	descending bool
+}{
+	"stake":           {func(v models.ValidatorListItem) float64 { return float64(v.Stake) }, true},
+	"efficiency":      {func(v models.ValidatorListItem) float64 { return v.AvgEfficiency }, false},
+	"index":           {func(v models.ValidatorListItem) float64 { return float64(v.Index) }, false},
+	"below_threshold": {func(v models.ValidatorListItem) float64 { return float64(v.SecondsBelowThreshold) }, true},
+}
+
+// listCursor points after the last validator of a page. It holds the sort
+// value rather than an offset so pages stay consistent when validators
+// appear or disappear between requests.
+type listCursor struct {
+	Sort  string  `json:"s"`
+	Desc  bool    `json:"d"`
+	Value float64 `json:"v"`
+	ADNL  string  `json:"a"`
+}
+
+func (c listCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(value string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+type validatorsFilter struct {
+	statuses      map[models.ValidatorStatus]bool
+	wallet        string
+	minStake      int64
+	minEfficiency float64
+	maxEfficiency float64
+	adnlPrefix    string
+}
+
+func (f validatorsFilter) matches(v models.ValidatorListItem) bool {
+	if len(f.statuses) > 0 && !f.statuses[v.Status] {
+		return false
+	}
+	if f.wallet != "" && v.WalletAddress != f.wallet {
+		return false
+	}
+	return v.Stake >= f.minStake &&
+		v.AvgEfficiency >= f.minEfficiency &&
+		v.AvgEfficiency <= f.maxEfficiency &&
+		strings.HasPrefix(v.ADNLAddr, f.adnlPrefix)
+}
+
+func parseValidatorsFilter(r *http.Request) (validatorsFilter, error) {
+	query := r.URL.Query()
+	filter := validatorsFilter{
+		wallet:        query.Get("wallet"),
+		maxEfficiency: 100,
+		adnlPrefix:    strings.ToUpper(query.Get("adnl")),
+	}
+	if value := query.Get("status"); value != "" {
+		filter.statuses = make(map[models.ValidatorStatus]bool)
+		for _, status := range strings.Split(value, ",") {
+			filter.statuses[models.ValidatorStatus(strings.TrimSpace(status))] = true
+		}
+	}
+	if value := query.Get("min_stake"); value != "" {
+		minStake, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid 'min_stake'")
+		}
+		filter.minStake = minStake
+	}
+	for name, target := range map[string]*float64{"min_efficiency": &filter.minEfficiency, "max_efficiency": &filter.maxEfficiency} {
+		if value := query.Get(name); value != "" {
+			efficiency, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return filter, fmt.Errorf("Invalid '%s'", name)
+			}
+			*target = efficiency
+		}
+	}
+	return filter, nil
+}
+
 type ValidatorsHandler struct {
 	ClickhouseService *services.ClickhouseService
 	CacheService      *services.CacheService
@@ -25,34 +126,32 @@ func NewValidatorsHandler(clickhouseService *services.ClickhouseService, cacheSe
 func (h *ValidatorsHandler) ValidatorStatusesHandler(w http.ResponseWriter, r *http.Request) {
 	fromStr := r.URL.Query().Get("from")
 	toStr := r.URL.Query().Get("to")
-	cycleIDParsed, _ := (strconv.ParseUint(r.URL.Query().Get("cycle_id"), 10, 32))
-	cycleID := uint32(cycleIDParsed)
-
 	if fromStr == "" || toStr == "" {
-		http.Error(w, "Required params: 'from'  'to'", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Required params: 'from', 'to'")
 		return
 	}
 
-	fromUnix, err := strconv.ParseInt(fromStr, 10, 64)
+	from, to, err := parseTimeRange(r)
 	if err != nil {
-		http.Error(w, "Invalid param 'from'", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	toUnix, err := strconv.ParseInt(toStr, 10, 64)
+	cycleID, err := parseCycleID(r)
 	if err != nil {
-		http.Error(w, "Invalid param 'to'", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	from := time.Unix(fromUnix, 0)
-	to := time.Unix(toUnix, 0)
-
 	statuses, err := h.ClickhouseService.GetValidatorsStatuses(from, to, cycleID, h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get validators statuses")
+		log.Printf("Failed to get validators statuses: %v", err)
+		return
+	}
 	meta, err := h.ClickhouseService.GetValidatorsMeta(from, to, cycleID, h.CacheService)
-
 	if err != nil {
-		http.Error(w, "Couldn't get validators statuses", http.StatusInternalServerError)
-		log.Fatal(err)
+		writeError(w, http.StatusInternalServerError, "Couldn't get validators statuses")
+		log.Printf("Failed to get validators meta: %v", err)
 		return
 	}
 
@@ -65,9 +164,127 @@ func (h *ValidatorsHandler) ValidatorStatusesHandler(w http.ResponseWriter, r *h
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(response)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// ListValidatorsHandler returns a page of validators summarized over the
+// range, filtered and sorted as requested. Efficiency series are included
+// only with series=true.
+func (h *ValidatorsHandler) ListValidatorsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to, err := parseTimeRange(r)
 	if err != nil {
-		http.Error(w, "Couldn't encode response", http.StatusInternalServerError)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	cycleID, err := parseCycleID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := parseValidatorsFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, ok := parseLimit(r, "limit", defaultListLimit, maxListLimit)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Invalid 'limit'")
+		return
+	}
+
+	sortName := query.Get("sort")
+	if sortName == "" {
+		sortName = "stake"
+	}
+	sortKey, ok := listSortKeys[sortName]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Invalid 'sort'; use stake, efficiency, index or below_threshold")
+		return
+	}
+	descending := sortKey.descending
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		writeError(w, http.StatusBadRequest, "Invalid 'order'; use asc or desc")
+		return
+	}
+
+	var cursor *listCursor
+	if value := query.Get("cursor"); value != "" {
+		cursor, err = decodeListCursor(value)
+		if err != nil || cursor.Sort != sortName || cursor.Desc != descending {
+			writeError(w, http.StatusBadRequest, "Invalid 'cursor'")
+			return
+		}
+	}
+
+	all, err := h.ClickhouseService.GetValidatorsList(from, to, cycleID, efficiencyThreshold(), h.CacheService)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get validators")
+		log.Printf("Failed to get validators list: %v", err)
+		return
+	}
+
+	// before reports whether a sorts before b; ties are broken by ADNL.
+	before := func(aValue float64, aADNL string, bValue float64, bADNL string) bool {
+		if aValue != bValue {
+			return (aValue < bValue) != descending
+		}
+		return aADNL < bADNL
+	}
+
+	matched := make([]models.ValidatorListItem, 0, len(all))
+	for _, v := range all {
+		if filter.matches(v) {
+			matched = append(matched, v)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return before(sortKey.value(matched[i]), matched[i].ADNLAddr, sortKey.value(matched[j]), matched[j].ADNLAddr)
+	})
+
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return before(cursor.Value, cursor.ADNL, sortKey.value(matched[i]), matched[i].ADNLAddr)
+		})
+	}
+	end := start + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	list := models.ValidatorList{
+		Validators: matched[start:end],
+		Total:      len(matched),
+	}
+	if end < len(matched) {
+		last := matched[end-1]
+		list.NextCursor = listCursor{Sort: sortName, Desc: descending, Value: sortKey.value(last), ADNL: last.ADNLAddr}.encode()
+	}
+
+	if query.Get("series") == "true" {
+		adnls := make([]string, len(list.Validators))
+		for i, v := range list.Validators {
+			adnls[i] = v.ADNLAddr
+		}
+		series, err := h.ClickhouseService.GetValidatorsSeries(adnls, from, to, cycleID, h.CacheService)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Couldn't get validators series")
+			log.Printf("Failed to get validators series: %v", err)
+			return
+		}
+		for i := range list.Validators {
+			list.Validators[i].Series = series[list.Validators[i].ADNLAddr]
+		}
+	}
+
+	writeJSON(w, http.StatusOK, list)
 }
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"validators-health/internal/models"
+	"validators-health/internal/services"
+	"validators-health/internal/webhooks"
+)
+
+const (
+	defaultDeliveriesLimit = 100
+	maxDeliveriesLimit     = 1000
+)
+
+type WebhooksHandler struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+}
+
+func NewWebhooksHandler(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *WebhooksHandler {
+	return &WebhooksHandler{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+	}
+}
+
+func (h *WebhooksHandler) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := h.CacheService.ListWebhooks()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get webhooks")
+		log.Printf("Failed to list webhooks: %v", err)
+		return
+	}
+	for i := range list {
+		list[i].Secret = ""
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *WebhooksHandler) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook, err := h.CacheService.GetWebhook(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get webhook")
+		log.Printf("Failed to get webhook %s: %v", r.PathValue("id"), err)
+		return
+	}
+	if webhook == nil {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	webhook.Secret = ""
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+// CreateWebhookHandler registers a webhook. The secret is generated when
+// not given and is only returned in this response.
+func (h *WebhooksHandler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		URL     string                    `json:"url"`
+		Secret  string                    `json:"secret"`
+		ADNLs   []string                  `json:"adnls"`
+		Wallets []string                  `json:"wallets"`
+		Events  []models.WebhookEventType `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		writeError(w, http.StatusBadRequest, "Invalid URL")
+		return
+	}
+	if err := webhooks.CheckURL(r.Context(), request.URL); err != nil {
+		if errors.Is(err, webhooks.ErrPrivateAddress) {
+			writeError(w, http.StatusBadRequest, "URL must resolve to a public address")
+		} else {
+			writeError(w, http.StatusBadRequest, "Couldn't resolve URL host")
+		}
+		return
+	}
+	for _, adnl := range request.ADNLs {
+		if !adnlPattern.MatchString(adnl) {
+			writeError(w, http.StatusBadRequest, "Invalid ADNL: "+adnl)
+			return
+		}
+	}
+	for _, event := range request.Events {
+		switch event {
+		case models.WebhookEventStatus, models.WebhookEventComplaint, models.WebhookEventIncident:
+		default:
+			writeError(w, http.StatusBadRequest, "Invalid event type: "+string(event))
+			return
+		}
+	}
+
+	webhook := models.Webhook{
+		ID:        webhooks.NewID(8),
+		URL:       request.URL,
+		Secret:    request.Secret,
+		ADNLs:     request.ADNLs,
+		Wallets:   request.Wallets,
+		Events:    request.Events,
+		CreatedAt: time.Now(),
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = webhooks.NewID(32)
+	}
+
+	if err := h.CacheService.SaveWebhook(webhook); err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't save webhook")
+		log.Printf("Failed to save webhook: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, webhook)
+}
+
+func (h *WebhooksHandler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	removed, err := h.CacheService.DeleteWebhook(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't delete webhook")
+		log.Printf("Failed to delete webhook %s: %v", id, err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WebhookDeliveriesHandler returns the most recent delivery attempts, up to
+// the 'limit' parameter.
+func (h *WebhooksHandler) WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	webhook, err := h.CacheService.GetWebhook(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get webhook")
+		log.Printf("Failed to get webhook %s: %v", id, err)
+		return
+	}
+	if webhook == nil {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	limit := defaultDeliveriesLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxDeliveriesLimit {
+			writeError(w, http.StatusBadRequest, "Invalid 'limit'")
+			return
+		}
+	}
+
+	deliveries, err := h.ClickhouseService.GetWebhookDeliveries(id, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Couldn't get deliveries")
+		log.Printf("Failed to get deliveries of webhook %s: %v", id, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
@@ -98,6 +98,59 @@ func CreateTables() error {
 		PRIMARY KEY (cycle_id, adnl_addr)
 		ORDER BY (cycle_id, adnl_addr);
 		`,
+
+		`
+		CREATE TABLE IF NOT EXISTS complaints
+		(
+		cycle_id            UInt32,
+		election_id         UInt32,
+		hash                String,
+		adnl_addr           String,
+		pubkey              String,
+		description         String,
+		created_time        DateTime,
+		severity            Int32,
+		suggested_fine      Int64,
+		suggested_fine_part Int64,
+		approved_percent    Float32,
+		is_passed           Bool
+		)
+		ENGINE = ReplacingMergeTree()
+		PRIMARY KEY (cycle_id, adnl_addr)
+		ORDER BY (cycle_id, adnl_addr, hash);
+		`,
+
+		`
+		CREATE TABLE IF NOT EXISTS audit_log
+		(
+		timestamp DateTime,
+		user_id   Int64,
+		username  String,
+		chat_id   Int64,
+		action    String,
+		details   String
+		)
+		ENGINE = MergeTree()
+		ORDER BY (timestamp, user_id);
+		`,
+
+		`
+		CREATE TABLE IF NOT EXISTS webhook_deliveries
+		(
+		timestamp   DateTime,
+		webhook_id  String,
+		event_id    String,
+		event_type  String,
+		attempt     UInt16,
+		status_code UInt16,
+		error       String,
+		success     Bool,
+		duration_ms UInt32
+		)
+		ENGINE = MergeTree()
+		ORDER BY (webhook_id, timestamp)
+		TTL timestamp + INTERVAL 30 DAY;
+		`,
 	}
 
 	for idx, query := range queries {
@@ -63,6 +63,9 @@ const (
 	StatusNotOK        ValidatorStatus = "not ok"
 	StatusAcknowledged ValidatorStatus = "acknowledged"
 	StatusUnknown      ValidatorStatus = "unknown"
+	// StatusMissing means the validator is in the active cycle but has been
+	// absent from the scoreboard for longer than the grace period.
+	StatusMissing ValidatorStatus = "missing"
 )
 
 type EfficiencyDataResponse struct {
@@ -100,3 +103,361 @@ type Meta map[string]struct {
 	AvgEfficiency float64 `json:"avg_efficiency"`
 	CycleID       uint32  `json:"cycle_id"`
 }
+
+type CycleReport struct {
+	CycleID    uint32                 `json:"cycle_id"`
+	UtimeSince int64                  `json:"utime_since"`
+	UtimeUntil int64                  `json:"utime_until"`
+	Threshold  float64                `json:"threshold"`
+	Validators []ValidatorCycleReport `json:"validators"`
+}
+
+type ValidatorCycleReport struct {
+	ADNLAddr      string `json:"adnl_addr" parquet:"adnl_addr"`
+	WalletAddress string `json:"wallet_address" parquet:"wallet_address"`
+	Index         uint16 `json:"index" parquet:"index"`
+	Samples       uint64 `json:"samples" parquet:"samples"`
+	// NoData marks validators without scoreboard samples in the cycle; their
+	// efficiency figures and exposure estimate are not meaningful.
+	NoData                bool    `json:"no_data" parquet:"no_data"`
+	AvgEfficiency         float64 `json:"avg_efficiency" parquet:"avg_efficiency"`
+	MinEfficiency         float64 `json:"min_efficiency" parquet:"min_efficiency"`
+	P5Efficiency          float64 `json:"p5_efficiency" parquet:"p5_efficiency"`
+	SecondsBelowThreshold int64   `json:"seconds_below_threshold" parquet:"seconds_below_threshold"`
+	StatusFlips           uint64  `json:"status_flips" parquet:"status_flips"`
+	Stake                 int64   `json:"stake" parquet:"stake"`
+	Weight                int64   `json:"weight" parquet:"weight"`
+	Complaints            uint64  `json:"complaints" parquet:"complaints"`
+	ExpectedRewardShare   float64 `json:"expected_reward_share" parquet:"expected_reward_share"`
+	EffectiveRewardShare  float64 `json:"effective_reward_share" parquet:"effective_reward_share"`
+	RewardShareLoss       float64 `json:"reward_share_loss" parquet:"reward_share_loss"`
+	EstimatedExposureTON  float64 `json:"estimated_exposure_ton" parquet:"estimated_exposure_ton"`
+}
+
+type ValidatorSLA struct {
+	ADNLAddr            string  `json:"adnl_addr"`
+	From                int64   `json:"from"`
+	To                  int64   `json:"to"`
+	OKPercent           float64 `json:"ok_percent"`
+	NotOKPercent        float64 `json:"not_ok_percent"`
+	AcknowledgedPercent float64 `json:"acknowledged_percent"`
+	UnknownPercent      float64 `json:"unknown_percent"`
+	Incidents           int     `json:"incidents"`
+	MTTRSeconds         float64 `json:"mttr_seconds"`
+	MTBFSeconds         float64 `json:"mtbf_seconds"`
+}
+
+type RewardExposure struct {
+	ExpectedRewardShare  float64 `json:"expected_reward_share"`
+	EffectiveRewardShare float64 `json:"effective_reward_share"`
+	RewardShareLoss      float64 `json:"reward_share_loss"`
+	EstimatedExposureTON float64 `json:"estimated_exposure_ton"`
+}
+
+type GroupKind string
+
+const (
+	GroupKindWallet GroupKind = "wallet"
+	GroupKindCustom GroupKind = "custom"
+)
+
+type ValidatorGroup struct {
+	Name    string    `json:"name"`
+	Kind    GroupKind `json:"kind"`
+	Members []string  `json:"members"`
+}
+
+type ValidatorSummary struct {
+	ADNLAddr      string  `json:"adnl_addr"`
+	AvgEfficiency float64 `json:"avg_efficiency"`
+	Stake         int64   `json:"stake"`
+}
+
+type GroupStats struct {
+	Name                    string    `json:"name"`
+	Kind                    GroupKind `json:"kind"`
+	Validators              int       `json:"validators"`
+	ActiveValidators        int       `json:"active_validators"`
+	AvgEfficiency           float64   `json:"avg_efficiency"`
+	MinEfficiency           float64   `json:"min_efficiency"`
+	StakeWeightedEfficiency float64   `json:"stake_weighted_efficiency"`
+	TotalStake              int64     `json:"total_stake"`
+	BelowThreshold          int       `json:"below_threshold"`
+}
+
+type NetworkHealth struct {
+	CycleID            uint32  `json:"cycle_id"`
+	NotOKWeightPercent float64 `json:"not_ok_weight_percent"`
+	NotOKValidators    int     `json:"not_ok_validators"`
+	Validators         int     `json:"validators"`
+}
+
+type EfficiencyBaseline struct {
+	ADNLAddr string  `json:"adnl_addr"`
+	Median   float64 `json:"median"`
+	MAD      float64 `json:"mad"`
+	Samples  uint64  `json:"samples"`
+}
+
+type EfficiencyAnomaly struct {
+	Efficiency    float64 `json:"efficiency"`
+	Baseline      float64 `json:"baseline"`
+	MAD           float64 `json:"mad"`
+	Score         float64 `json:"score"`
+	Samples       uint64  `json:"samples"`
+	BaselineHours int     `json:"baseline_hours"`
+}
+
+type IngestionHealth struct {
+	LastSampleAt        time.Time `json:"last_sample_at"`
+	LastCyclesFetchAt   time.Time `json:"last_cycles_fetch_at"`
+	LagThresholdSeconds int64     `json:"lag_threshold_seconds"`
+	Stalled             bool      `json:"stalled"`
+}
+
+type ValidatorDigest struct {
+	ValidatorADNL         string  `json:"validator_adnl"`
+	AvgEfficiency         float64 `json:"avg_efficiency"`
+	HasData               bool    `json:"has_data"`
+	SecondsBelowThreshold int64   `json:"seconds_below_threshold"`
+	Incidents             uint64  `json:"incidents"`
+	Acks                  uint64  `json:"acks"`
+	Complaints            uint64  `json:"complaints"`
+}
+
+type ValidatorInfo struct {
+	CycleID       uint32 `json:"cycle_id"`
+	ADNLAddr      string `json:"adnl_addr"`
+	PubKey        string `json:"pubkey"`
+	Weight        int64  `json:"weight"`
+	Index         uint16 `json:"index"`
+	Stake         int64  `json:"stake"`
+	MaxFactor     int32  `json:"max_factor"`
+	WalletAddress string `json:"wallet_address"`
+}
+
+type AuditLogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	ChatID    int64     `json:"chat_id"`
+	Action    string    `json:"action"`
+	Details   string    `json:"details"`
+}
+
+type StreamEventType string
+
+const (
+	StreamEventSample StreamEventType = "sample"
+	StreamEventStatus StreamEventType = "status"
+)
+
+// StreamEvent is a scoreboard sample or a status transition published to
+// live stream clients.
+type StreamEvent struct {
+	// ID is the event's position in the stream, used to resume it.
+	ID             string          `json:"id,omitempty"`
+	Type           StreamEventType `json:"type"`
+	Timestamp      time.Time       `json:"timestamp"`
+	CycleID        uint32          `json:"cycle_id"`
+	ADNLAddr       string          `json:"adnl_addr"`
+	ValidatorADNL  string          `json:"validator_adnl"`
+	Efficiency     float64         `json:"efficiency"`
+	Status         ValidatorStatus `json:"status,omitempty"`
+	PreviousStatus string          `json:"previous_status,omitempty"`
+}
+
+type WebhookEventType string
+
+const (
+	WebhookEventStatus    WebhookEventType = "status"
+	WebhookEventComplaint WebhookEventType = "complaint"
+	WebhookEventIncident  WebhookEventType = "incident"
+)
+
+// Webhook is an integration endpoint that receives events matching its
+// filters. Empty filters match everything.
+type Webhook struct {
+	ID        string             `json:"id"`
+	URL       string             `json:"url"`
+	Secret    string             `json:"secret,omitempty"`
+	ADNLs     []string           `json:"adnls,omitempty"`
+	Wallets   []string           `json:"wallets,omitempty"`
+	Events    []WebhookEventType `json:"events,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// WebhookEvent is the JSON body posted to webhooks. Incidents are
+// network-wide and carry no validator.
+type WebhookEvent struct {
+	ID             string           `json:"id"`
+	Type           WebhookEventType `json:"type"`
+	Timestamp      time.Time        `json:"timestamp"`
+	CycleID        uint32           `json:"cycle_id,omitempty"`
+	ADNLAddr       string           `json:"adnl_addr,omitempty"`
+	ValidatorADNL  string           `json:"validator_adnl,omitempty"`
+	Efficiency     float64          `json:"efficiency,omitempty"`
+	Status         ValidatorStatus  `json:"status,omitempty"`
+	PreviousStatus string           `json:"previous_status,omitempty"`
+	Complaint      *Complaint       `json:"complaint,omitempty"`
+	Incident       string           `json:"incident,omitempty"`
+	Network        *NetworkHealth   `json:"network,omitempty"`
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	Timestamp  time.Time        `json:"timestamp"`
+	WebhookID  string           `json:"webhook_id"`
+	EventID    string           `json:"event_id"`
+	EventType  WebhookEventType `json:"event_type"`
+	Attempt    int              `json:"attempt"`
+	StatusCode int              `json:"status_code"`
+	Error      string           `json:"error,omitempty"`
+	Success    bool             `json:"success"`
+	DurationMs int64            `json:"duration_ms"`
+}
+
+type APIKeyScope string
+
+const (
+	APIKeyScopeRead  APIKeyScope = "read"
+	APIKeyScopeAdmin APIKeyScope = "admin"
+)
+
+// APIKey describes an API key. Only the key's hash is stored.
+type APIKey struct {
+	ID    string      `json:"id"`
+	Name  string      `json:"name"`
+	Scope APIKeyScope `json:"scope"`
+	// RateLimit is the number of requests per minute; zero means the default.
+	RateLimit int       `json:"rate_limit,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidatorCycleStats is a validator's election entry and efficiency in one
+// cycle it participated in.
+type ValidatorCycleStats struct {
+	CycleID               uint32  `json:"cycle_id"`
+	UtimeSince            int64   `json:"utime_since"`
+	UtimeUntil            int64   `json:"utime_until"`
+	Index                 uint16  `json:"index"`
+	Stake                 int64   `json:"stake"`
+	Weight                int64   `json:"weight"`
+	MaxFactor             int32   `json:"max_factor"`
+	AvgEfficiency         float64 `json:"avg_efficiency"`
+	MinEfficiency         float64 `json:"min_efficiency"`
+	BelowThresholdPercent float64 `json:"below_threshold_percent"`
+	Samples               uint64  `json:"samples"`
+	Complaints            uint64  `json:"complaints"`
+}
+
+type ValidatorComplaint struct {
+	CycleID           uint32    `json:"cycle_id"`
+	ElectionID        uint32    `json:"election_id"`
+	Hash              string    `json:"hash"`
+	Description       string    `json:"description"`
+	CreatedTime       time.Time `json:"created_time"`
+	Severity          int32     `json:"severity"`
+	SuggestedFine     int64     `json:"suggested_fine"`
+	SuggestedFinePart int64     `json:"suggested_fine_part"`
+	ApprovedPercent   float32   `json:"approved_percent"`
+	IsPassed          bool      `json:"is_passed"`
+}
+
+// ValidatorDetail is everything known about one validator.
+type ValidatorDetail struct {
+	ADNLAddr      string                   `json:"adnl_addr"`
+	PubKey        string                   `json:"pubkey"`
+	WalletAddress string                   `json:"wallet_address"`
+	Status        ValidatorStatus          `json:"status"`
+	StatusSince   *time.Time               `json:"status_since,omitempty"`
+	Cycles        []ValidatorCycleStats    `json:"cycles"`
+	StatusHistory []ValidatorStatusHistory `json:"status_history"`
+	Complaints    []ValidatorComplaint     `json:"complaints"`
+	Subscribers   int                      `json:"subscribers"`
+}
+
+// ValidatorListItem summarizes a validator over a time range.
+type ValidatorListItem struct {
+	ADNLAddr              string             `json:"adnl_addr"`
+	WalletAddress         string             `json:"wallet_address"`
+	CycleID               uint32             `json:"cycle_id"`
+	Index                 uint16             `json:"index"`
+	Stake                 int64              `json:"stake"`
+	Weight                int64              `json:"weight"`
+	Status                ValidatorStatus    `json:"status"`
+	AvgEfficiency         float64            `json:"avg_efficiency"`
+	SecondsBelowThreshold int64              `json:"seconds_below_threshold"`
+	Series                map[uint32]float64 `json:"series,omitempty"`
+}
+
+type ValidatorList struct {
+	Validators []ValidatorListItem `json:"validators"`
+	Total      int                 `json:"total"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// NetworkBand is the spread of validators' efficiency in one interval.
+type NetworkBand struct {
+	Timestamp uint32  `json:"timestamp"`
+	P10       float64 `json:"p10"`
+	Median    float64 `json:"median"`
+	P90       float64 `json:"p90"`
+}
+
+// CycleRank places a validator's average efficiency in a cycle among all
+// validators of that cycle: 0 is the worst, 100 the best.
+type CycleRank struct {
+	CycleID        uint32  `json:"cycle_id"`
+	AvgEfficiency  float64 `json:"avg_efficiency"`
+	PercentileRank float64 `json:"percentile_rank"`
+	Validators     uint64  `json:"validators"`
+}
+
+type ValidatorComparison struct {
+	ADNLAddr string             `json:"adnl_addr"`
+	Series   map[uint32]float64 `json:"series"`
+	Ranks    []CycleRank        `json:"ranks"`
+}
+
+type Comparison struct {
+	From       int64                 `json:"from"`
+	To         int64                 `json:"to"`
+	Network    []NetworkBand         `json:"network"`
+	Validators []ValidatorComparison `json:"validators"`
+}
+
+type StakeEventType string
+
+const (
+	StakeEventChanged  StakeEventType = "stake_changed"
+	StakeEventLeft     StakeEventType = "elections_left"
+	StakeEventRejoined StakeEventType = "elections_rejoined"
+)
+
+// StakeHistoryEntry is a validator's election entry in one cycle, compared
+// with the last cycle it took part in before.
+type StakeHistoryEntry struct {
+	CycleID       uint32         `json:"cycle_id"`
+	UtimeSince    int64          `json:"utime_since"`
+	UtimeUntil    int64          `json:"utime_until"`
+	Participated  bool           `json:"participated"`
+	Stake         int64          `json:"stake"`
+	Weight        int64          `json:"weight"`
+	MaxFactor     int32          `json:"max_factor"`
+	StakeChange   int64          `json:"stake_change"`
+	ChangePercent float64        `json:"change_percent"`
+	Event         StakeEventType `json:"event,omitempty"`
+}
+
+// StakeChange describes a detected stake or participation change.
+type StakeChange struct {
+	Event           StakeEventType `json:"event"`
+	CycleID         uint32         `json:"cycle_id"`
+	PreviousCycleID uint32         `json:"previous_cycle_id"`
+	Stake           int64          `json:"stake"`
+	PreviousStake   int64          `json:"previous_stake"`
+	ChangePercent   float64        `json:"change_percent"`
+	MissedCycles    int            `json:"missed_cycles"`
+	WalletAddress   string         `json:"wallet_address"`
+}
@@ -0,0 +1,507 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	broadcastIDKey      = "broadcast_id"
+	activeBroadcastsKey = "broadcasts_active"
+
+	broadcastStatusSent    = "sent"
+	broadcastStatusBlocked = "blocked"
+	broadcastStatusFailed  = "failed"
+
+	// broadcastDrainTimeout is how long a broadcast without pending
+	// recipients waits for results of messages still being sent by other
+	// replicas before it is reported.
+	broadcastDrainTimeout = time.Minute
+	maxReportedFailures   = 20
+)
+
+// Broadcast is an announcement being delivered to every subscribed chat.
+// Recipients are moved from a pending to a processing list while a message
+// is sent to them, so any replica can resume it and a recipient whose
+// sender died is retried.
+type Broadcast struct {
+	ID   int64  `json:"id"`
+	Text string `json:"text"`
+	// Markdown sends the text with Telegram's Markdown parse mode; otherwise
+	// it is sent as plain text.
+	Markdown  bool      `json:"markdown,omitempty"`
+	AdminChat int64     `json:"admin_chat"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Total     int       `json:"total"`
+}
+
+func broadcastKey(id int64) string {
+	return fmt.Sprintf("broadcast:%d", id)
+}
+
+func broadcastPendingKey(id int64) string {
+	return fmt.Sprintf("broadcast:%d:pending", id)
+}
+
+func broadcastProcessingKey(id int64) string {
+	return fmt.Sprintf("broadcast:%d:processing", id)
+}
+
+func broadcastResultsKey(id int64) string {
+	return fmt.Sprintf("broadcast:%d:results", id)
+}
+
+// broadcastMessagesPerSecond is the number of broadcast messages all
+// replicas together may send per second, below Telegram's limit of about 30
+// to leave room for alerts.
+func broadcastMessagesPerSecond() float64 {
+	rate, err := strconv.ParseFloat(os.Getenv("BROADCAST_MESSAGES_PER_SECOND"), 64)
+	if err != nil || rate <= 0 {
+		return 20
+	}
+	return rate
+}
+
+func announcementText(lang Language, a announcement) string {
+	return fmt.Sprintf("%s\n\n%s", tr(lang, "announce.header"), a.text(lang))
+}
+
+// handleAnnounce queues an announcement for every subscribed chat. With
+// --preview it is only sent to the admin, in each of its languages, and
+// with --dry-run only the recipients are counted. The text is sent as plain
+// text unless --markdown is given.
+func (n *Notifier) handleAnnounce(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	if update.Message.From == nil || !n.isAdmin(update.Message.From.ID) {
+		n.reply(ctx, chatID, tr(lang, "announce.unauthorized"))
+		return
+	}
+
+	args := strings.SplitN(update.Message.Text, " ", 2)
+	if len(args) < 2 {
+		n.reply(ctx, chatID, tr(lang, "announce.usage"))
+		return
+	}
+	text := strings.TrimSpace(args[1])
+	var mode string
+	markdown := false
+	for strings.HasPrefix(text, "--") {
+		parts := strings.SplitN(text, " ", 2)
+		switch {
+		case parts[0] == "--markdown":
+			markdown = true
+		case (parts[0] == "--dry-run" || parts[0] == "--preview") && mode == "":
+			mode = parts[0]
+		default:
+			n.reply(ctx, chatID, tr(lang, "announce.usage"))
+			return
+		}
+		text = ""
+		if len(parts) == 2 {
+			text = strings.TrimSpace(parts[1])
+		}
+	}
+	if text == "" {
+		n.reply(ctx, chatID, tr(lang, "announce.usage"))
+		return
+	}
+	a := parseAnnouncement(text)
+
+	if mode == "--preview" {
+		for _, previewLang := range supportedLanguages {
+			if _, ok := a[previewLang]; !ok && previewLang != lang {
+				continue
+			}
+			if err := n.sendAnnouncement(ctx, chatID, announcementText(previewLang, a), markdown); err != nil {
+				log.Printf("Failed to send announcement preview to chat %d: %v", chatID, err)
+				n.reply(ctx, chatID, tr(lang, "announce.failed")+"\n"+err.Error())
+				return
+			}
+		}
+		return
+	}
+
+	subscribers, err := n.redisClient.SMembers(ctx, GlobalSubscriptionKey).Result()
+	if err != nil {
+		log.Printf("Failed to get global subscribers: %v", err)
+		n.reply(ctx, chatID, tr(lang, "announce.failed"))
+		return
+	}
+
+	if mode == "--dry-run" {
+		byLanguage := make(map[Language]int)
+		for _, chatIDStr := range subscribers {
+			recipient, err := strconv.ParseInt(chatIDStr, 10, 64)
+			if err != nil {
+				continue
+			}
+			byLanguage[n.chatLanguage(recipient)]++
+		}
+		var counts []string
+		for _, l := range supportedLanguages {
+			if byLanguage[l] > 0 {
+				counts = append(counts, fmt.Sprintf("%s: %d", l, byLanguage[l]))
+			}
+		}
+		n.reply(ctx, chatID, tr(lang, "announce.dry_run", len(subscribers), strings.Join(counts, ", ")))
+		return
+	}
+
+	broadcast, err := n.queueBroadcast(text, markdown, chatID, update.Message.From.ID, subscribers)
+	if err != nil {
+		log.Printf("Failed to queue broadcast: %v", err)
+		n.reply(ctx, chatID, tr(lang, "announce.failed"))
+		return
+	}
+	n.audit(update.Message, "announce", fmt.Sprintf("broadcast %d to %d recipients: %s", broadcast.ID, broadcast.Total, text))
+	n.reply(ctx, chatID, tr(lang, "announce.queued", broadcast.ID, broadcast.Total))
+}
+
+func (n *Notifier) queueBroadcast(text string, markdown bool, adminChat, createdBy int64, recipients []string) (*Broadcast, error) {
+	id, err := n.redisClient.Incr(ctx, broadcastIDKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	broadcast := &Broadcast{
+		ID:        id,
+		Text:      text,
+		Markdown:  markdown,
+		AdminChat: adminChat,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		Total:     len(recipients),
+	}
+	data, err := json.Marshal(broadcast)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := n.redisClient.TxPipeline()
+	pipe.Set(ctx, broadcastKey(id), data, 0)
+	if len(recipients) > 0 {
+		values := make([]interface{}, len(recipients))
+		for i, recipient := range recipients {
+			values[i] = recipient
+		}
+		pipe.RPush(ctx, broadcastPendingKey(id), values...)
+	}
+	pipe.SAdd(ctx, activeBroadcastsKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return broadcast, nil
+}
+
+func (n *Notifier) getBroadcast(id int64) (*Broadcast, error) {
+	data, err := n.redisClient.Get(ctx, broadcastKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var broadcast Broadcast
+	if err := json.Unmarshal([]byte(data), &broadcast); err != nil {
+		return nil, err
+	}
+	return &broadcast, nil
+}
+
+// RunBroadcasts delivers queued broadcasts, one message per interval, until
+// stopped.
+func (n *Notifier) RunBroadcasts(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / broadcastMessagesPerSecond()))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if retryAfter := n.sendNextBroadcastMessage(); retryAfter > 0 {
+				select {
+				case <-stop:
+					return
+				case <-time.After(retryAfter):
+				}
+			}
+		}
+	}
+}
+
+// sendNextBroadcastMessage sends one message of the oldest active broadcast.
+// It returns how long to pause when Telegram asks to slow down.
+func (n *Notifier) sendNextBroadcastMessage() time.Duration {
+	ids, err := n.redisClient.SMembers(ctx, activeBroadcastsKey).Result()
+	if err != nil {
+		log.Printf("Failed to get active broadcasts: %v", err)
+		return 0
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ids[i], 10, 64)
+		b, _ := strconv.ParseInt(ids[j], 10, 64)
+		return a < b
+	})
+
+	for _, idStr := range ids {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		broadcast, err := n.getBroadcast(id)
+		if err != nil {
+			log.Printf("Failed to get broadcast %d: %v", id, err)
+			continue
+		}
+		if broadcast == nil {
+			n.redisClient.SRem(ctx, activeBroadcastsKey, idStr)
+			continue
+		}
+
+		// The broadcast budget is shared by all replicas, and broadcasts
+		// share the global budget and pauses with alerts.
+		if n.sendingPaused() || !n.acquireRateSlot("broadcast_rate", int64(math.Ceil(broadcastMessagesPerSecond()))) || !n.acquireGlobalSlot() {
+			return 0
+		}
+		recipient, err := n.redisClient.LMove(ctx, broadcastPendingKey(id), broadcastProcessingKey(id), "LEFT", "RIGHT").Result()
+		if errors.Is(err, redis.Nil) {
+			n.finishBroadcast(broadcast)
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to get next recipient of broadcast %d: %v", id, err)
+			return 0
+		}
+		return n.deliverBroadcast(broadcast, recipient)
+	}
+	return 0
+}
+
+func (n *Notifier) deliverBroadcast(broadcast *Broadcast, recipient string) time.Duration {
+	chatID, err := strconv.ParseInt(recipient, 10, 64)
+	if err != nil {
+		n.recordBroadcastResult(broadcast, recipient, broadcastStatusFailed+": invalid chat ID")
+		return 0
+	}
+
+	lang := n.chatLanguage(chatID)
+	err = n.sendAnnouncement(ctx, chatID, announcementText(lang, parseAnnouncement(broadcast.Text)), broadcast.Markdown)
+
+	var tooManyRequests *bot.TooManyRequestsError
+	switch {
+	case err == nil:
+		n.recordBroadcastResult(broadcast, recipient, broadcastStatusSent)
+	case errors.As(err, &tooManyRequests):
+		// Put the recipient back in front and pause the whole broadcast.
+		pipe := n.redisClient.TxPipeline()
+		pipe.LPush(ctx, broadcastPendingKey(broadcast.ID), recipient)
+		pipe.LRem(ctx, broadcastProcessingKey(broadcast.ID), 1, recipient)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("Failed to requeue recipient %s of broadcast %d: %v", recipient, broadcast.ID, err)
+		}
+		retryAfter := time.Duration(tooManyRequests.RetryAfter) * time.Second
+		n.pauseSending(retryAfter)
+		return retryAfter
+	case errors.Is(err, bot.ErrorForbidden):
+		// The bot was blocked or removed from the chat.
+		if err := n.removeChat(chatID); err != nil {
+			log.Printf("Failed to remove blocked chat %d: %v", chatID, err)
+		}
+		n.recordBroadcastResult(broadcast, recipient, broadcastStatusBlocked)
+	default:
+		log.Printf("Failed to send broadcast %d to chat %d: %v", broadcast.ID, chatID, err)
+		n.recordBroadcastResult(broadcast, recipient, broadcastStatusFailed+": "+err.Error())
+	}
+	return 0
+}
+
+// recordBroadcastResult stores the result for the recipient and only then
+// removes it from the processing list.
+func (n *Notifier) recordBroadcastResult(broadcast *Broadcast, recipient, status string) {
+	resultsKey := broadcastResultsKey(broadcast.ID)
+	if err := n.redisClient.HSet(ctx, resultsKey, recipient, status).Err(); err != nil {
+		log.Printf("Failed to record result of broadcast %d for chat %s: %v", broadcast.ID, recipient, err)
+		return
+	}
+	if err := n.redisClient.LRem(ctx, broadcastProcessingKey(broadcast.ID), 1, recipient).Err(); err != nil {
+		log.Printf("Failed to ack recipient %s of broadcast %d: %v", recipient, broadcast.ID, err)
+	}
+	processed, err := n.redisClient.HLen(ctx, resultsKey).Result()
+	if err == nil && int(processed) >= broadcast.Total {
+		n.finishBroadcast(broadcast)
+	}
+}
+
+// finishBroadcast reports a broadcast without pending recipients once all
+// results are in. Recipients still being processed after the drain timeout
+// were left by a replica that stopped mid-send and are queued again.
+func (n *Notifier) finishBroadcast(broadcast *Broadcast) {
+	processed, err := n.redisClient.HLen(ctx, broadcastResultsKey(broadcast.ID)).Result()
+	if err != nil {
+		log.Printf("Failed to get results of broadcast %d: %v", broadcast.ID, err)
+		return
+	}
+	if int(processed) < broadcast.Total {
+		drainedKey := fmt.Sprintf("broadcast:%d:drained", broadcast.ID)
+		n.redisClient.SetNX(ctx, drainedKey, time.Now().Unix(), 24*time.Hour)
+		drainedAt, err := n.redisClient.Get(ctx, drainedKey).Int64()
+		if err != nil || time.Since(time.Unix(drainedAt, 0)) < broadcastDrainTimeout {
+			return
+		}
+		if n.requeueStalledRecipients(broadcast) > 0 {
+			n.redisClient.Del(ctx, drainedKey)
+			return
+		}
+	}
+
+	// Several replicas may notice the end of the broadcast at once.
+	reportKey := fmt.Sprintf("broadcast:%d:reported", broadcast.ID)
+	acquired, err := n.redisClient.SetNX(ctx, reportKey, 1, 0).Result()
+	if err != nil || !acquired {
+		return
+	}
+	if err := n.redisClient.SRem(ctx, activeBroadcastsKey, broadcast.ID).Err(); err != nil {
+		log.Printf("Failed to deactivate broadcast %d: %v", broadcast.ID, err)
+	}
+
+	lang := n.chatLanguage(broadcast.AdminChat)
+	results, err := n.redisClient.HGetAll(ctx, broadcastResultsKey(broadcast.ID)).Result()
+	if err != nil {
+		log.Printf("Failed to get results of broadcast %d: %v", broadcast.ID, err)
+		return
+	}
+	sent, blocked, failures := summarizeBroadcast(results)
+	text := tr(lang, "broadcast.report", broadcast.ID, sent, blocked, len(failures))
+	if len(failures) > 0 {
+		if len(failures) > maxReportedFailures {
+			failures = append(failures[:maxReportedFailures], "…")
+		}
+		text += "\n\n" + tr(lang, "broadcast.failed_list") + "\n" + strings.Join(failures, "\n")
+	}
+	n.reply(ctx, broadcast.AdminChat, text)
+	log.Printf("Broadcast %d finished: %d sent, %d blocked, %d failed", broadcast.ID, sent, blocked, len(failures))
+}
+
+// requeueStalledRecipients moves recipients without a result from the
+// processing list back to the pending one and returns how many were moved.
+func (n *Notifier) requeueStalledRecipients(broadcast *Broadcast) int {
+	processingKey := broadcastProcessingKey(broadcast.ID)
+	stalled, err := n.redisClient.LRange(ctx, processingKey, 0, -1).Result()
+	if err != nil {
+		log.Printf("Failed to get processing recipients of broadcast %d: %v", broadcast.ID, err)
+		return 0
+	}
+
+	requeued := 0
+	for _, recipient := range stalled {
+		done, err := n.redisClient.HExists(ctx, broadcastResultsKey(broadcast.ID), recipient).Result()
+		if err != nil {
+			log.Printf("Failed to check result of broadcast %d for chat %s: %v", broadcast.ID, recipient, err)
+			continue
+		}
+		pipe := n.redisClient.TxPipeline()
+		pipe.LRem(ctx, processingKey, 1, recipient)
+		if !done {
+			pipe.RPush(ctx, broadcastPendingKey(broadcast.ID), recipient)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("Failed to requeue recipient %s of broadcast %d: %v", recipient, broadcast.ID, err)
+			continue
+		}
+		if !done {
+			requeued++
+		}
+	}
+	if requeued > 0 {
+		log.Printf("Requeued %d stalled recipients of broadcast %d", requeued, broadcast.ID)
+	}
+	return requeued
+}
+
+// summarizeBroadcast counts delivered and blocked recipients and lists the
+// failed ones with their errors.
+func summarizeBroadcast(results map[string]string) (sent, blocked int, failures []string) {
+	for recipient, status := range results {
+		switch status {
+		case broadcastStatusSent:
+			sent++
+		case broadcastStatusBlocked:
+			blocked++
+		default:
+			failures = append(failures, fmt.Sprintf("%s: %s", recipient, strings.TrimPrefix(status, broadcastStatusFailed+": ")))
+		}
+	}
+	sort.Strings(failures)
+	return sent, blocked, failures
+}
+
+func (n *Notifier) sendAnnouncement(ctx context.Context, chatID int64, text string, markdown bool) error {
+	msg := &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	}
+	if markdown {
+		msg.ParseMode = "Markdown"
+	}
+	_, err := n.bot.SendMessage(ctx, msg)
+	return err
+}
+
+// handleBroadcast shows the progress of a broadcast.
+func (n *Notifier) handleBroadcast(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	if update.Message.From == nil || !n.isAdmin(update.Message.From.ID) {
+		n.reply(ctx, chatID, tr(lang, "announce.unauthorized"))
+		return
+	}
+
+	args := commandArgs(update.Message.Text)
+	if len(args) != 1 {
+		n.reply(ctx, chatID, tr(lang, "broadcast.usage"))
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+	if err != nil {
+		n.reply(ctx, chatID, tr(lang, "broadcast.usage"))
+		return
+	}
+
+	broadcast, err := n.getBroadcast(id)
+	if err != nil {
+		log.Printf("Failed to get broadcast %d: %v", id, err)
+	}
+	if broadcast == nil {
+		n.reply(ctx, chatID, tr(lang, "broadcast.not_found"))
+		return
+	}
+	results, err := n.redisClient.HGetAll(ctx, broadcastResultsKey(id)).Result()
+	if err != nil {
+		log.Printf("Failed to get results of broadcast %d: %v", id, err)
+	}
+	sent, blocked, failures := summarizeBroadcast(results)
+	n.reply(ctx, chatID, tr(lang, "broadcast.status", id, len(results), broadcast.Total, sent, blocked, len(failures)))
+}
@@ -0,0 +1,283 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	digestSettingsKey = "digest_settings"
+	digestPeriodDaily = "daily"
+	// Weekly digests go out on Mondays.
+	digestPeriodWeekly = "weekly"
+)
+
+type DigestSettings struct {
+	Period   string    `json:"period"`
+	Time     string    `json:"time"`
+	Timezone string    `json:"timezone"`
+	LastSent time.Time `json:"last_sent"`
+}
+
+func (d DigestSettings) length() time.Duration {
+	if d.Period == digestPeriodWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// lastScheduled returns the latest moment at or before now when the digest
+// was due.
+func (d DigestSettings) lastScheduled(now time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	clock, err := time.Parse("15:04", d.Time)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := now.In(loc)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	if d.Period == digestPeriodWeekly {
+		for scheduled.Weekday() != time.Monday {
+			scheduled = scheduled.AddDate(0, 0, -1)
+		}
+	}
+	return scheduled, nil
+}
+
+func (n *Notifier) handleDigest(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	chatKey := strconv.FormatInt(chatID, 10)
+	lang := n.messageLanguage(update.Message)
+	args := strings.Fields(update.Message.Text)
+	usage := tr(lang, "digest.usage")
+
+	if len(args) == 1 {
+		data, err := n.redisClient.HGet(ctx, digestSettingsKey, chatKey).Result()
+		if err != nil {
+			n.reply(ctx, chatID, tr(lang, "digest.off")+"\n"+usage)
+			return
+		}
+		var settings DigestSettings
+		if err := json.Unmarshal([]byte(data), &settings); err != nil {
+			n.reply(ctx, chatID, tr(lang, "digest.off")+"\n"+usage)
+			return
+		}
+		n.reply(ctx, chatID, tr(lang, "digest.current", settings.Period, settings.Time, settings.Timezone))
+		return
+	}
+
+	if args[1] == "off" {
+		if err := n.redisClient.HDel(ctx, digestSettingsKey, chatKey).Err(); err != nil {
+			log.Printf("Failed to disable digest for chat %d: %v", chatID, err)
+			n.reply(ctx, chatID, tr(lang, "digest.failed"))
+			return
+		}
+		n.reply(ctx, chatID, tr(lang, "digest.disabled"))
+		return
+	}
+
+	if len(args) < 3 || (args[1] != digestPeriodDaily && args[1] != digestPeriodWeekly) {
+		n.reply(ctx, chatID, usage)
+		return
+	}
+
+	settings := DigestSettings{
+		Period:   args[1],
+		Time:     args[2],
+		Timezone: "UTC",
+	}
+	if len(args) > 3 {
+		settings.Timezone = args[3]
+	}
+
+	// Start counting from the latest due moment so enabling a digest after
+	// today's time doesn't trigger one immediately.
+	scheduled, err := settings.lastScheduled(time.Now())
+	if err != nil {
+		n.reply(ctx, chatID, tr(lang, "digest.invalid"))
+		return
+	}
+	settings.LastSent = scheduled
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		log.Printf("Failed to serialize digest settings: %v", err)
+		return
+	}
+	if err := n.redisClient.HSet(ctx, digestSettingsKey, chatKey, data).Err(); err != nil {
+		log.Printf("Failed to save digest settings for chat %d: %v", chatID, err)
+		n.reply(ctx, chatID, tr(lang, "digest.failed"))
+		return
+	}
+
+	n.reply(ctx, chatID, tr(lang, "digest.scheduled", settings.Period, settings.Time, settings.Timezone))
+}
+
+// RunDigests sends scheduled digests until stopped.
+func (n *Notifier) RunDigests(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			n.sendDueDigests()
+		}
+	}
+}
+
+func (n *Notifier) sendDueDigests() {
+	allSettings, err := n.redisClient.HGetAll(ctx, digestSettingsKey).Result()
+	if err != nil {
+		log.Printf("Failed to get digest settings: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for chatKey, data := range allSettings {
+		chatID, err := strconv.ParseInt(chatKey, 10, 64)
+		if err != nil {
+			log.Printf("Invalid chat ID: %v", err)
+			continue
+		}
+
+		var settings DigestSettings
+		if err := json.Unmarshal([]byte(data), &settings); err != nil {
+			log.Printf("Invalid digest settings for chat %d: %v", chatID, err)
+			continue
+		}
+
+		scheduled, err := settings.lastScheduled(now)
+		if err != nil || !scheduled.After(settings.LastSent) {
+			continue
+		}
+
+		// Several replicas run this loop; only one of them may send.
+		lockKey := fmt.Sprintf("digest_lock:%d:%d", chatID, scheduled.Unix())
+		acquired, err := n.redisClient.SetNX(ctx, lockKey, 1, 24*time.Hour).Result()
+		if err != nil || !acquired {
+			continue
+		}
+
+		if err := n.sendDigest(chatID, settings, scheduled); err != nil {
+			log.Printf("Failed to send digest to chat %d: %v", chatID, err)
+			// Let the next run, on this or another replica, try again.
+			if err := n.redisClient.Del(ctx, lockKey).Err(); err != nil {
+				log.Printf("Failed to release digest lock for chat %d: %v", chatID, err)
+			}
+			continue
+		}
+
+		settings.LastSent = scheduled
+		updated, err := json.Marshal(settings)
+		if err != nil {
+			continue
+		}
+		if err := n.redisClient.HSet(ctx, digestSettingsKey, chatKey, updated).Err(); err != nil {
+			log.Printf("Failed to update digest settings for chat %d: %v", chatID, err)
+		}
+	}
+}
+
+func (n *Notifier) sendDigest(chatID int64, settings DigestSettings, scheduled time.Time) error {
+	adnls, err := n.getChatSubscriptions(chatID)
+	if err != nil {
+		return err
+	}
+	if len(adnls) == 0 {
+		return nil
+	}
+
+	threshold, _ := strconv.ParseFloat(os.Getenv("EFFICIENCY_THRESHOLD"), 64)
+	from := scheduled.Add(-settings.length())
+	digests, err := n.ClickhouseService.GetDigest(adnls, from, scheduled, threshold)
+	if err != nil {
+		return err
+	}
+
+	lang := n.chatLanguage(chatID)
+	blocks := []string{tr(lang, "digest.title."+settings.Period, from.Format("2006-01-02 15:04"), scheduled.Format("2006-01-02 15:04"), settings.Timezone)}
+	for _, digest := range digests {
+		var sb strings.Builder
+		sb.WriteString(digest.ValidatorADNL + "\n")
+		if !digest.HasData {
+			sb.WriteString(tr(lang, "digest.no_data") + "\n")
+		} else {
+			sb.WriteString(tr(lang, "digest.avg_efficiency", digest.AvgEfficiency) + "\n")
+			sb.WriteString(tr(lang, "digest.below_threshold", formatDuration(lang, time.Duration(digest.SecondsBelowThreshold)*time.Second)) + "\n")
+		}
+		sb.WriteString(tr(lang, "digest.incidents", digest.Incidents, digest.Acks) + "\n")
+		sb.WriteString(tr(lang, "digest.complaints", digest.Complaints))
+		blocks = append(blocks, sb.String())
+	}
+
+	for _, text := range joinBlocks(blocks, "\n\n", maxMessageLength) {
+		n.enqueue(chatID, OutboundMessage{Text: text})
+	}
+	return nil
+}
+
+// joinBlocks joins the blocks with the separator into as few texts as
+// possible, each at most limit UTF-16 code units long. Blocks longer than
+// the limit are cut.
+func joinBlocks(blocks []string, separator string, limit int) []string {
+	var texts []string
+	current := ""
+	for _, block := range blocks {
+		if current != "" && utf16Length(current)+utf16Length(separator)+utf16Length(block) <= limit {
+			current += separator + block
+			continue
+		}
+		if current != "" {
+			texts = append(texts, current)
+		}
+		for utf16Length(block) > limit {
+			head, rest := cutUTF16(block, limit)
+			texts = append(texts, head)
+			block = rest
+		}
+		current = block
+	}
+	if current != "" {
+		texts = append(texts, current)
+	}
+	return texts
+}
+
+// cutUTF16 splits s after at most limit UTF-16 code units.
+func cutUTF16(s string, limit int) (string, string) {
+	length := 0
+	for i, r := range s {
+		size := 1
+		if r >= 0x10000 {
+			size = 2
+		}
+		if length+size > limit {
+			return s[:i], s[i:]
+		}
+		length += size
+	}
+	return s, ""
+}
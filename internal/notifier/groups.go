@@ -0,0 +1,139 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func groupSubscriptionKey(group string) string {
+	return fmt.Sprintf("group_subscription_%s", group)
+}
+
+// notifyGroups forwards the alert to chats subscribed to any group of the
+// validator, together with the current health of that group. Chats that
+// already got the alert directly are skipped.
+func (n *Notifier) notifyGroups(alert Alert, messages *alertMessages, alreadyNotified []string) {
+	groups, err := n.ClickhouseService.GetGroupsOf(alert.ADNLAddr, n.CacheService)
+	if err != nil {
+		log.Printf("Failed to get groups for ADNL %s: %v", alert.ADNLAddr, err)
+		return
+	}
+
+	notified := make(map[string]bool, len(alreadyNotified))
+	for _, chatIDStr := range alreadyNotified {
+		notified[chatIDStr] = true
+	}
+
+	for _, group := range groups {
+		subscriptions, err := n.redisClient.SMembers(ctx, groupSubscriptionKey(group.Name)).Result()
+		if err != nil {
+			log.Printf("Failed to get subscriptions for group %s: %v", group.Name, err)
+			continue
+		}
+		if len(subscriptions) == 0 {
+			continue
+		}
+
+		notOK, err := n.ClickhouseService.CountNotOK(group.Members)
+		if err != nil {
+			log.Printf("Failed to get health of group %s: %v", group.Name, err)
+			continue
+		}
+
+		for _, chatIDStr := range subscriptions {
+			if notified[chatIDStr] {
+				continue
+			}
+			chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+			if err != nil {
+				log.Printf("Invalid chat ID: %v", err)
+				continue
+			}
+			notified[chatIDStr] = true
+			lang := n.chatLanguage(chatID)
+			groupMessage := messages.get(chatID, lang).WithLine(tr(lang, "alert.group_health", group.Name, group.Kind, notOK, len(group.Members)))
+			n.sendMessage(chatID, lang, groupMessage, alert)
+		}
+	}
+}
+
+func (n *Notifier) handleAddGroup(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	args := strings.Fields(update.Message.Text)
+	if len(args) < 2 {
+		n.reply(ctx, chatID, tr(lang, "addgroup.usage"))
+		return
+	}
+
+	group, err := n.ClickhouseService.ResolveGroup(args[1], n.CacheService)
+	if err != nil {
+		log.Printf("Failed to resolve group %s: %v", args[1], err)
+		n.reply(ctx, chatID, tr(lang, "addgroup.failed"))
+		return
+	}
+	if group == nil {
+		n.reply(ctx, chatID, tr(lang, "addgroup.unknown"))
+		return
+	}
+
+	err = n.subscribe(chatID, groupSubscriptionKey(group.Name))
+	if err != nil {
+		log.Printf("Failed to add subscription for group %s: %v", group.Name, err)
+		n.reply(ctx, chatID, tr(lang, "addgroup.failed"))
+		return
+	}
+
+	err = n.redisClient.SAdd(ctx, GlobalSubscriptionKey, chatID).Err()
+	if err != nil {
+		log.Printf("Failed to add to global subscribers list: %v", err)
+	}
+
+	n.reply(ctx, chatID, tr(lang, "addgroup.done", group.Name, len(group.Members)))
+}
+
+func (n *Notifier) handleDelGroup(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	args := strings.Fields(update.Message.Text)
+	if len(args) < 2 {
+		n.reply(ctx, chatID, tr(lang, "delgroup.usage"))
+		return
+	}
+
+	err := n.unsubscribe(chatID, groupSubscriptionKey(args[1]))
+	if err != nil {
+		log.Printf("Failed to remove subscription for group %s: %v", args[1], err)
+		n.reply(ctx, chatID, tr(lang, "delgroup.failed"))
+		return
+	}
+
+	n.removeIfUnsubscribed(chatID)
+
+	n.reply(ctx, chatID, tr(lang, "delgroup.done", args[1]))
+}
+
+func (n *Notifier) reply(ctx context.Context, chatID int64, text string) {
+	msg := &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	}
+	_, err := n.bot.SendMessage(ctx, msg)
+	if err != nil {
+		log.Printf("Failed to send message to chat %d: %v", chatID, err)
+	}
+}
@@ -0,0 +1,456 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+)
+
+type Language string
+
+const (
+	LangEN Language = "en"
+	LangRU Language = "ru"
+	LangZH Language = "zh"
+
+	defaultLanguage = LangEN
+
+	chatLanguageKey         = "chat_language"
+	chatDetectedLanguageKey = "chat_language_detected"
+)
+
+var supportedLanguages = []Language{LangEN, LangRU, LangZH}
+
+// parseLanguage maps a Telegram language_code such as "ru" or "zh-hans" to
+// a supported language.
+func parseLanguage(code string) (Language, bool) {
+	code = strings.ToLower(code)
+	for _, lang := range supportedLanguages {
+		if code == string(lang) || strings.HasPrefix(code, string(lang)+"-") {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// catalog holds every bot message by language. Values are fmt formats;
+// missing translations fall back to English.
+var catalog = map[Language]map[string]string{
+	LangEN: {
+		"alert.now":                 "Validator %s is now %s",
+		"alert.previous":            "Previous state %s, duration: %s.",
+		"alert.missing":             "The validator is absent from the scoreboard of the active cycle.",
+		"alert.efficiency":          "Efficiency: %s%%",
+		"alert.exposure":            "Estimated exposure: %s TON",
+		"alert.details":             "Check details at: %s",
+		"alert.network_degraded":    "Network-wide degradation in cycle %d: %s%% of total weight (%d of %d validators) is not ok.",
+		"alert.network_held":        "Individual validator alerts are held back until the network recovers.",
+		"alert.network_recovered":   "Network recovered in cycle %d after %s: %s%% of total weight (%d of %d validators) is not ok.",
+		"alert.network_resumed":     "Individual validator alerts are resumed.",
+		"alert.degraded":            "Validator %s is degraded",
+		"alert.degraded_evidence":   "Efficiency %s%% vs baseline %s%% (MAD %s, %sσ below over %d samples in the last %dh)",
+		"alert.degradation_cleared": "Validator %s is back to its baseline efficiency (%s%%) after %s.",
+		"alert.ingestion_stalled":   "Ingestion stalled: no fresh data for more than %s.",
+		"alert.ingestion_resumed":   "Ingestion resumed after %s.",
+		"alert.last_sample":         "Last scoreboard sample: %s",
+		"alert.last_cycles_fetch":   "Last successful cycles fetch: %s",
+		"alert.stake_changed":       "Validator %s changed its stake from %s to %s TON (%s%%) between cycles %d and %d.",
+		"alert.elections_left":      "Validator %s has not been elected for %d cycles since cycle %d (stake %s TON).",
+		"alert.elections_rejoined":  "Validator %s is elected again in cycle %d with %s TON after missing %d cycles.",
+		"alert.group_health":        "Group %s (%s): %d of %d validators not ok",
+		"status.ok":                 "ok",
+		"status.not ok":             "not ok",
+		"status.missing":            "missing",
+		"status.acknowledged":       "acknowledged",
+		"never":                     "never",
+		"duration":                  "%dh %d min",
+		"button.ack":                "Acknowledge",
+		"ack.no_such_alert":         "No such alert.",
+		"ack.done":                  "Acknowledged by",
+		"add.usage":                 "Usage: /add <ADNL> [ADNL...]",
+		"add.invalid":               "Invalid ADNL format. ADNL must be a 64-character hex string (uppercase, A-F, 0-9).",
+		"add.failed":                "Failed to subscribe to alerts.",
+		"add.done":                  "Subscribed to alerts for:\n%s",
+		"del.usage":                 "Usage: /del <ADNL> [ADNL...]",
+		"del.failed":                "Failed to unsubscribe from alerts.",
+		"del.done":                  "Unsubscribed from alerts for:\n%s",
+		"addwallet.usage":           "Usage: /addwallet <wallet>",
+		"addwallet.failed":          "Failed to subscribe to wallet alerts.",
+		"addwallet.done":            "Subscribed to alerts for %d validators of wallet %s. New validators of the wallet are added automatically.",
+		"delwallet.usage":           "Usage: /delwallet <wallet>",
+		"delwallet.failed":          "Failed to unsubscribe from wallet alerts.",
+		"delwallet.done":            "Unsubscribed from alerts for wallet %s",
+		"clear.failed":              "Failed to remove subscriptions.",
+		"clear.done":                "All subscriptions of this chat are removed.",
+		"addgroup.usage":            "Usage: /addgroup <wallet|group>",
+		"addgroup.unknown":          "Unknown group. Use an owner wallet address or the name of a defined group.",
+		"addgroup.failed":           "Failed to subscribe to group alerts.",
+		"addgroup.done":             "Subscribed to alerts for group %s (%d validators)",
+		"delgroup.usage":            "Usage: /delgroup <wallet|group>",
+		"delgroup.failed":           "Failed to unsubscribe from group alerts.",
+		"delgroup.done":             "Unsubscribed from alerts for group %s",
+		"sla.usage":                 "Usage: /sla <ADNL> [days]",
+		"sla.invalid_days":          "Invalid number of days. Use a value between 1 and 365.",
+		"sla.failed":                "Failed to compute SLA.",
+		"sla.report":                "SLA for %s over the last %d days:\n\nOK: %.2f%%\nNot OK: %.2f%%\nAcknowledged: %.2f%%\nUnknown (no data): %.2f%%\n\nIncidents: %d",
+		"sla.mttr":                  "MTTR: %s",
+		"sla.mtbf":                  "MTBF: %s",
+		"digest.usage":              "Usage: /digest <daily|weekly> <HH:MM> [timezone] or /digest off",
+		"digest.off":                "Digests are off.",
+		"digest.current":            "Digest: %s at %s (%s)",
+		"digest.disabled":           "Digests disabled.",
+		"digest.failed":             "Failed to update digest settings.",
+		"digest.invalid":            "Invalid time or timezone. Example: /digest daily 09:00 Europe/Berlin",
+		"digest.scheduled":          "Digest scheduled: %s at %s (%s)",
+		"digest.title.daily":        "📊 Daily digest %s – %s (%s)",
+		"digest.title.weekly":       "📊 Weekly digest %s – %s (%s)",
+		"digest.no_data":            "No data for the period",
+		"digest.avg_efficiency":     "Avg efficiency: %.2f%%",
+		"digest.below_threshold":    "Below threshold: %s",
+		"digest.incidents":          "Incidents: %d, acknowledged: %d",
+		"digest.complaints":         "Complaints: %d",
+		"stake.usage":               "Usage: /stake <on|off> - alerts about stake changes and election participation of your validators",
+		"stake.on":                  "Stake alerts are on.",
+		"stake.off":                 "Stake alerts are off.",
+		"stake.enabled":             "Stake alerts enabled.",
+		"stake.disabled":            "Stake alerts disabled.",
+		"stake.failed":              "Failed to update stake alert settings.",
+		"announce.unauthorized":     "You are not authorized to use this command.",
+		"announce.usage":            "Usage: /announce [--dry-run|--preview] [--markdown] <message>",
+		"announce.queued":           "Broadcast #%d queued for %d recipients. Use /broadcast %[1]d to follow it.",
+		"announce.dry_run":          "Dry run: the announcement would be sent to %d recipients (%s). Nothing was sent.",
+		"broadcast.usage":           "Usage: /broadcast <id>",
+		"broadcast.not_found":       "No such broadcast.",
+		"broadcast.status":          "Broadcast #%d: %d of %d processed, %d sent, %d removed (bot blocked), %d failed.",
+		"broadcast.report":          "Broadcast #%d finished: %d sent, %d removed (bot blocked), %d failed.",
+		"broadcast.failed_list":     "Failed recipients:",
+		"announce.failed":           "Failed to send announcement.",
+		"announce.header":           "📢 Announcement:",
+		"role.usage":                "Usage: /role <user_id> <admin|member|none>",
+		"role.list":                 "Roles:",
+		"role.forbidden":            "You can't change this role.",
+		"role.failed":               "Failed to change the role.",
+		"role.done":                 "User %d is now %s.",
+		"role.members_only":         "This bot is available to members only. Ask an admin for access.",
+		"role.chat_admins_only":     "Only chat administrators can change the subscriptions of this chat.",
+		"lang.usage":                "Usage: /lang <%s>",
+		"lang.done":                 "Language set to English.",
+		"lang.failed":               "Failed to save the language.",
+		"format.usage":              "Usage: /format <plain|markdownv2|html|default>\nCurrent format: %s",
+		"format.done":               "Alerts will use the %s format.",
+		"format.failed":             "Failed to save the format.",
+		"help":                      "Unknown command. Available commands:\n/add <ADNL> [ADNL...] - Subscribe to alerts\n/del <ADNL> [ADNL...] - Unsubscribe from alerts\n/addwallet <wallet> - Subscribe to all validators of a wallet\n/delwallet <wallet> - Unsubscribe from a wallet\n/clear - Remove all subscriptions\n/addgroup <wallet|group> - Subscribe to a validator group\n/delgroup <wallet|group> - Unsubscribe from a validator group\n/sla <ADNL> [days] - Show uptime statistics\n/digest <daily|weekly> <HH:MM> [timezone] - Schedule a summary report\n/stake <on|off> - Alerts about stake changes and elections\n/lang <en|ru|zh> - Change the bot language\n/format <plain|markdownv2|html|default> - Change the alert format",
+	},
+	LangRU: {
+		"alert.now":                 "Валидатор %s теперь в состоянии %s",
+		"alert.previous":            "Предыдущее состояние %s, длительность: %s.",
+		"alert.missing":             "Валидатор отсутствует в таблице результатов активного цикла.",
+		"alert.efficiency":          "Эффективность: %s%%",
+		"alert.exposure":            "Оценка возможного штрафа: %s TON",
+		"alert.details":             "Подробнее: %s",
+		"alert.network_degraded":    "Деградация всей сети в цикле %d: %s%% общего веса (%d из %d валидаторов) не в порядке.",
+		"alert.network_held":        "Оповещения по отдельным валидаторам приостановлены до восстановления сети.",
+		"alert.network_recovered":   "Сеть восстановилась в цикле %d через %s: %s%% общего веса (%d из %d валидаторов) не в порядке.",
+		"alert.network_resumed":     "Оповещения по отдельным валидаторам возобновлены.",
+		"alert.degraded":            "Эффективность валидатора %s снизилась",
+		"alert.degraded_evidence":   "Эффективность %s%% при базовой %s%% (MAD %s, на %sσ ниже по %d замерам за последние %d ч)",
+		"alert.degradation_cleared": "Эффективность валидатора %s вернулась к базовой (%s%%) через %s.",
+		"alert.ingestion_stalled":   "Сбор данных остановился: нет новых данных более %s.",
+		"alert.ingestion_resumed":   "Сбор данных возобновился через %s.",
+		"alert.last_sample":         "Последний замер: %s",
+		"alert.last_cycles_fetch":   "Последняя успешная загрузка циклов: %s",
+		"alert.stake_changed":       "Стейк валидатора %s изменился с %s до %s TON (%s%%) между циклами %d и %d.",
+		"alert.elections_left":      "Валидатор %s не избирается уже %d цикла(ов) после цикла %d (стейк %s TON).",
+		"alert.elections_rejoined":  "Валидатор %s снова избран в цикле %d со стейком %s TON после пропуска %d цикла(ов).",
+		"alert.group_health":        "Группа %s (%s): %d из %d валидаторов не в порядке",
+		"status.ok":                 "в порядке",
+		"status.not ok":             "не в порядке",
+		"status.missing":            "отсутствует",
+		"status.acknowledged":       "подтверждено",
+		"never":                     "никогда",
+		"duration":                  "%d ч %d мин",
+		"button.ack":                "Подтвердить",
+		"ack.no_such_alert":         "Такого оповещения нет.",
+		"ack.done":                  "Подтвердил(а)",
+		"add.usage":                 "Использование: /add <ADNL> [ADNL...]",
+		"add.invalid":               "Неверный формат ADNL. ADNL — это строка из 64 шестнадцатеричных символов (A-F, 0-9 в верхнем регистре).",
+		"add.failed":                "Не удалось подписаться на оповещения.",
+		"add.done":                  "Вы подписаны на оповещения для:\n%s",
+		"del.usage":                 "Использование: /del <ADNL> [ADNL...]",
+		"del.failed":                "Не удалось отписаться от оповещений.",
+		"del.done":                  "Вы отписаны от оповещений для:\n%s",
+		"addwallet.usage":           "Использование: /addwallet <кошелёк>",
+		"addwallet.failed":          "Не удалось подписаться на оповещения кошелька.",
+		"addwallet.done":            "Вы подписаны на оповещения для %d валидаторов кошелька %s. Новые валидаторы кошелька добавляются автоматически.",
+		"delwallet.usage":           "Использование: /delwallet <кошелёк>",
+		"delwallet.failed":          "Не удалось отписаться от оповещений кошелька.",
+		"delwallet.done":            "Вы отписаны от оповещений кошелька %s",
+		"clear.failed":              "Не удалось удалить подписки.",
+		"clear.done":                "Все подписки этого чата удалены.",
+		"addgroup.usage":            "Использование: /addgroup <кошелёк|группа>",
+		"addgroup.unknown":          "Неизвестная группа. Укажите адрес кошелька владельца или имя созданной группы.",
+		"addgroup.failed":           "Не удалось подписаться на оповещения группы.",
+		"addgroup.done":             "Вы подписаны на оповещения группы %s (%d валидаторов)",
+		"delgroup.usage":            "Использование: /delgroup <кошелёк|группа>",
+		"delgroup.failed":           "Не удалось отписаться от оповещений группы.",
+		"delgroup.done":             "Вы отписаны от оповещений группы %s",
+		"sla.usage":                 "Использование: /sla <ADNL> [дней]",
+		"sla.invalid_days":          "Неверное число дней. Укажите значение от 1 до 365.",
+		"sla.failed":                "Не удалось рассчитать SLA.",
+		"sla.report":                "SLA для %s за последние %d дн.:\n\nВ порядке: %.2f%%\nНе в порядке: %.2f%%\nПодтверждено: %.2f%%\nНеизвестно (нет данных): %.2f%%\n\nИнцидентов: %d",
+		"sla.mttr":                  "MTTR: %s",
+		"sla.mtbf":                  "MTBF: %s",
+		"digest.usage":              "Использование: /digest <daily|weekly> <ЧЧ:ММ> [часовой пояс] или /digest off",
+		"digest.off":                "Сводки отключены.",
+		"digest.current":            "Сводка: %s в %s (%s)",
+		"digest.disabled":           "Сводки отключены.",
+		"digest.failed":             "Не удалось обновить настройки сводки.",
+		"digest.invalid":            "Неверное время или часовой пояс. Пример: /digest daily 09:00 Europe/Moscow",
+		"digest.scheduled":          "Сводка запланирована: %s в %s (%s)",
+		"digest.title.daily":        "📊 Ежедневная сводка %s – %s (%s)",
+		"digest.title.weekly":       "📊 Еженедельная сводка %s – %s (%s)",
+		"digest.no_data":            "Нет данных за период",
+		"digest.avg_efficiency":     "Средняя эффективность: %.2f%%",
+		"digest.below_threshold":    "Ниже порога: %s",
+		"digest.incidents":          "Инцидентов: %d, подтверждено: %d",
+		"digest.complaints":         "Жалоб: %d",
+		"stake.usage":               "Использование: /stake <on|off> - оповещения об изменениях стейка и участии ваших валидаторов в выборах",
+		"stake.on":                  "Оповещения о стейке включены.",
+		"stake.off":                 "Оповещения о стейке выключены.",
+		"stake.enabled":             "Оповещения о стейке включены.",
+		"stake.disabled":            "Оповещения о стейке выключены.",
+		"stake.failed":              "Не удалось обновить настройки оповещений о стейке.",
+		"announce.unauthorized":     "У вас нет прав на эту команду.",
+		"announce.usage":            "Использование: /announce [--dry-run|--preview] [--markdown] <сообщение>",
+		"announce.queued":           "Рассылка #%d поставлена в очередь для %d получателей. Следите за ней через /broadcast %[1]d.",
+		"announce.dry_run":          "Пробный запуск: объявление получили бы %d получателей (%s). Ничего не отправлено.",
+		"broadcast.usage":           "Использование: /broadcast <id>",
+		"broadcast.not_found":       "Такой рассылки нет.",
+		"broadcast.status":          "Рассылка #%d: обработано %d из %d, отправлено %d, удалено (бот заблокирован) %d, ошибок %d.",
+		"broadcast.report":          "Рассылка #%d завершена: отправлено %d, удалено (бот заблокирован) %d, ошибок %d.",
+		"broadcast.failed_list":     "Не доставлено:",
+		"announce.failed":           "Не удалось отправить объявление.",
+		"announce.header":           "📢 Объявление:",
+		"role.usage":                "Использование: /role <user_id> <admin|member|none>",
+		"role.list":                 "Роли:",
+		"role.forbidden":            "Вы не можете изменить эту роль.",
+		"role.failed":               "Не удалось изменить роль.",
+		"role.done":                 "Пользователь %d теперь %s.",
+		"role.members_only":         "Бот доступен только участникам. Попросите администратора о доступе.",
+		"role.chat_admins_only":     "Только администраторы чата могут менять подписки этого чата.",
+		"lang.usage":                "Использование: /lang <%s>",
+		"lang.done":                 "Язык изменён на русский.",
+		"lang.failed":               "Не удалось сохранить язык.",
+		"format.usage":              "Использование: /format <plain|markdownv2|html|default>\nТекущий формат: %s",
+		"format.done":               "Оповещения будут в формате %s.",
+		"format.failed":             "Не удалось сохранить формат.",
+		"help":                      "Неизвестная команда. Доступные команды:\n/add <ADNL> [ADNL...] - Подписаться на оповещения\n/del <ADNL> [ADNL...] - Отписаться от оповещений\n/addwallet <кошелёк> - Подписаться на все валидаторы кошелька\n/delwallet <кошелёк> - Отписаться от кошелька\n/clear - Удалить все подписки\n/addgroup <кошелёк|группа> - Подписаться на группу валидаторов\n/delgroup <кошелёк|группа> - Отписаться от группы валидаторов\n/sla <ADNL> [дней] - Статистика доступности\n/digest <daily|weekly> <ЧЧ:ММ> [часовой пояс] - Настроить сводку\n/stake <on|off> - Оповещения о стейке и выборах\n/lang <en|ru|zh> - Сменить язык бота\n/format <plain|markdownv2|html|default> - Сменить формат оповещений",
+	},
+	LangZH: {
+		"alert.now":                 "验证者 %s 当前状态：%s",
+		"alert.previous":            "之前状态 %s，持续时间：%s。",
+		"alert.missing":             "该验证者不在当前周期的记分板中。",
+		"alert.efficiency":          "效率：%s%%",
+		"alert.exposure":            "预计罚款风险：%s TON",
+		"alert.details":             "查看详情：%s",
+		"alert.network_degraded":    "周期 %d 出现全网性能下降：%s%% 的总权重（%d / %d 个验证者）状态异常。",
+		"alert.network_held":        "在网络恢复之前，单个验证者的告警将暂缓发送。",
+		"alert.network_recovered":   "周期 %d 的网络已在 %s 后恢复：%s%% 的总权重（%d / %d 个验证者）状态异常。",
+		"alert.network_resumed":     "单个验证者的告警已恢复发送。",
+		"alert.degraded":            "验证者 %s 性能下降",
+		"alert.degraded_evidence":   "效率 %s%%，基线 %s%%（MAD %s，低于基线 %sσ，基于最近 %[6]d 小时内的 %[5]d 个样本）",
+		"alert.degradation_cleared": "验证者 %s 的效率已在 %[3]s 后恢复到基线水平（%[2]s%%）。",
+		"alert.ingestion_stalled":   "数据采集停滞：超过 %s 没有新数据。",
+		"alert.ingestion_resumed":   "数据采集已在 %s 后恢复。",
+		"alert.last_sample":         "最新记分板样本：%s",
+		"alert.last_cycles_fetch":   "最近一次成功获取周期：%s",
+		"alert.stake_changed":       "验证者 %s 的质押在周期 %[5]d 到 %[6]d 之间从 %[2]s 变为 %[3]s TON（%[4]s%%）。",
+		"alert.elections_left":      "验证者 %s 自周期 %[3]d 起已连续 %[2]d 个周期未当选（质押 %[4]s TON）。",
+		"alert.elections_rejoined":  "验证者 %s 在错过 %[4]d 个周期后于周期 %[2]d 重新当选，质押 %[3]s TON。",
+		"alert.group_health":        "分组 %s（%s）：%[3]d / %[4]d 个验证者状态异常",
+		"status.ok":                 "正常",
+		"status.not ok":             "异常",
+		"status.missing":            "缺失",
+		"status.acknowledged":       "已确认",
+		"never":                     "从未",
+		"duration":                  "%d小时%d分钟",
+		"button.ack":                "确认",
+		"ack.no_such_alert":         "没有此告警。",
+		"ack.done":                  "确认人",
+		"add.usage":                 "用法：/add <ADNL> [ADNL...]",
+		"add.invalid":               "ADNL 格式无效。ADNL 必须是 64 位十六进制字符串（大写 A-F、0-9）。",
+		"add.failed":                "订阅告警失败。",
+		"add.done":                  "已订阅以下 ADNL 的告警：\n%s",
+		"del.usage":                 "用法：/del <ADNL> [ADNL...]",
+		"del.failed":                "取消订阅失败。",
+		"del.done":                  "已取消订阅以下 ADNL 的告警：\n%s",
+		"addwallet.usage":           "用法：/addwallet <钱包>",
+		"addwallet.failed":          "订阅钱包告警失败。",
+		"addwallet.done":            "已订阅钱包 %[2]s 的 %[1]d 个验证者的告警。该钱包的新验证者将自动加入。",
+		"delwallet.usage":           "用法：/delwallet <钱包>",
+		"delwallet.failed":          "取消订阅钱包告警失败。",
+		"delwallet.done":            "已取消订阅钱包 %s 的告警",
+		"clear.failed":              "删除订阅失败。",
+		"clear.done":                "已删除此聊天的所有订阅。",
+		"addgroup.usage":            "用法：/addgroup <钱包|分组>",
+		"addgroup.unknown":          "未知分组。请使用所有者钱包地址或已定义分组的名称。",
+		"addgroup.failed":           "订阅分组告警失败。",
+		"addgroup.done":             "已订阅分组 %s 的告警（%d 个验证者）",
+		"delgroup.usage":            "用法：/delgroup <钱包|分组>",
+		"delgroup.failed":           "取消订阅分组告警失败。",
+		"delgroup.done":             "已取消订阅分组 %s 的告警",
+		"sla.usage":                 "用法：/sla <ADNL> [天数]",
+		"sla.invalid_days":          "天数无效。请输入 1 到 365 之间的值。",
+		"sla.failed":                "计算 SLA 失败。",
+		"sla.report":                "%s 最近 %d 天的 SLA：\n\n正常：%.2f%%\n异常：%.2f%%\n已确认：%.2f%%\n未知（无数据）：%.2f%%\n\n事件数：%d",
+		"sla.mttr":                  "平均修复时间：%s",
+		"sla.mtbf":                  "平均故障间隔：%s",
+		"digest.usage":              "用法：/digest <daily|weekly> <HH:MM> [时区] 或 /digest off",
+		"digest.off":                "摘要已关闭。",
+		"digest.current":            "摘要：%s，%s（%s）",
+		"digest.disabled":           "摘要已关闭。",
+		"digest.failed":             "更新摘要设置失败。",
+		"digest.invalid":            "时间或时区无效。示例：/digest daily 09:00 Asia/Shanghai",
+		"digest.scheduled":          "已设置摘要：%s，%s（%s）",
+		"digest.title.daily":        "📊 每日摘要 %s – %s（%s）",
+		"digest.title.weekly":       "📊 每周摘要 %s – %s（%s）",
+		"digest.no_data":            "该时段无数据",
+		"digest.avg_efficiency":     "平均效率：%.2f%%",
+		"digest.below_threshold":    "低于阈值时长：%s",
+		"digest.incidents":          "事件：%d，已确认：%d",
+		"digest.complaints":         "投诉：%d",
+		"stake.usage":               "用法：/stake <on|off> - 关于您的验证者质押变化和参选情况的告警",
+		"stake.on":                  "质押告警已开启。",
+		"stake.off":                 "质押告警已关闭。",
+		"stake.enabled":             "已开启质押告警。",
+		"stake.disabled":            "已关闭质押告警。",
+		"stake.failed":              "更新质押告警设置失败。",
+		"announce.unauthorized":     "您无权使用此命令。",
+		"announce.usage":            "用法：/announce [--dry-run|--preview] [--markdown] <消息>",
+		"announce.queued":           "广播 #%d 已排队，共 %d 个接收者。使用 /broadcast %[1]d 查看进度。",
+		"announce.dry_run":          "试运行：公告将发送给 %d 个接收者（%s）。未发送任何消息。",
+		"broadcast.usage":           "用法：/broadcast <id>",
+		"broadcast.not_found":       "没有此广播。",
+		"broadcast.status":          "广播 #%d：已处理 %d / %d，已发送 %d，已移除（机器人被屏蔽）%d，失败 %d。",
+		"broadcast.report":          "广播 #%d 已完成：已发送 %d，已移除（机器人被屏蔽）%d，失败 %d。",
+		"broadcast.failed_list":     "发送失败的接收者：",
+		"announce.failed":           "发送公告失败。",
+		"announce.header":           "📢 公告：",
+		"role.usage":                "用法：/role <user_id> <admin|member|none>",
+		"role.list":                 "角色：",
+		"role.forbidden":            "您无法更改此角色。",
+		"role.failed":               "更改角色失败。",
+		"role.done":                 "用户 %d 现在是 %s。",
+		"role.members_only":         "此机器人仅对成员开放。请联系管理员获取权限。",
+		"role.chat_admins_only":     "只有群管理员可以更改此聊天的订阅。",
+		"lang.usage":                "用法：/lang <%s>",
+		"lang.done":                 "语言已设置为中文。",
+		"lang.failed":               "保存语言失败。",
+		"format.usage":              "用法：/format <plain|markdownv2|html|default>\n当前格式：%s",
+		"format.done":               "告警将使用 %s 格式。",
+		"format.failed":             "保存格式失败。",
+		"help":                      "未知命令。可用命令：\n/add <ADNL> [ADNL...] - 订阅告警\n/del <ADNL> [ADNL...] - 取消订阅告警\n/addwallet <钱包> - 订阅钱包的所有验证者\n/delwallet <钱包> - 取消订阅钱包\n/clear - 删除所有订阅\n/addgroup <钱包|分组> - 订阅验证者分组\n/delgroup <钱包|分组> - 取消订阅验证者分组\n/sla <ADNL> [天数] - 查看可用性统计\n/digest <daily|weekly> <HH:MM> [时区] - 设置摘要报告\n/stake <on|off> - 质押变化和参选告警\n/lang <en|ru|zh> - 更改机器人语言\n/format <plain|markdownv2|html|default> - 更改告警格式",
+	},
+}
+
+// tr returns the message for the key in the language, formatted with args.
+func tr(lang Language, key string, args ...interface{}) string {
+	format, ok := catalog[lang][key]
+	if !ok {
+		format, ok = catalog[defaultLanguage][key]
+	}
+	if !ok {
+		log.Printf("Missing message %q", key)
+		format = key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// statusName returns the localized name of a validator status.
+func statusName(lang Language, status string) string {
+	if _, ok := catalog[defaultLanguage]["status."+status]; !ok {
+		return status
+	}
+	return tr(lang, "status."+status)
+}
+
+// chatLanguage returns the language chosen with /lang, or the one detected
+// from the Telegram user's language_code, or English.
+func (n *Notifier) chatLanguage(chatID int64) Language {
+	chatKey := strconv.FormatInt(chatID, 10)
+	for _, key := range []string{chatLanguageKey, chatDetectedLanguageKey} {
+		code, err := n.redisClient.HGet(ctx, key, chatKey).Result()
+		if err != nil {
+			continue
+		}
+		if lang, ok := parseLanguage(code); ok {
+			return lang
+		}
+	}
+	return defaultLanguage
+}
+
+// messageLanguage resolves the language for replying to a message and
+// remembers the sender's language_code for later alerts to the chat.
+func (n *Notifier) messageLanguage(message *models.Message) Language {
+	chatID := message.Chat.ID
+	if message.From != nil {
+		if lang, ok := parseLanguage(message.From.LanguageCode); ok {
+			err := n.redisClient.HSet(ctx, chatDetectedLanguageKey, strconv.FormatInt(chatID, 10), string(lang)).Err()
+			if err != nil {
+				log.Printf("Failed to save detected language for chat %d: %v", chatID, err)
+			}
+		}
+	}
+	return n.chatLanguage(chatID)
+}
+
+var announcementSectionPattern = regexp.MustCompile(`\[(en|ru|zh)\]`)
+
+// announcement holds the text of an announcement by language. Sections are
+// marked with [en], [ru] or [zh]; text before the first marker is kept under
+// the empty language.
+type announcement map[Language]string
+
+func parseAnnouncement(text string) announcement {
+	a := make(announcement)
+	matches := announcementSectionPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		a[""] = strings.TrimSpace(text)
+		return a
+	}
+
+	if head := strings.TrimSpace(text[:matches[0][0]]); head != "" {
+		a[""] = head
+	}
+	for i, match := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		lang := Language(text[match[2]:match[3]])
+		a[lang] = strings.TrimSpace(text[match[1]:end])
+	}
+	return a
+}
+
+// text returns the section for the language, falling back to English and
+// then to the unmarked text.
+func (a announcement) text(lang Language) string {
+	for _, l := range []Language{lang, defaultLanguage, ""} {
+		if text, ok := a[l]; ok {
+			return text
+		}
+	}
+	for _, text := range a {
+		return text
+	}
+	return ""
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/go-telegram/bot/models"
 	"log"
 	"os"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -22,22 +22,27 @@ import (
 var ctx = context.Background()
 
 const GlobalSubscriptionKey = "global_subscribers"
-const MaxMessagesPerMinute = 20
-
-var adminChatIDs = []int64{
-	1531459, // @mobyman
-}
 
 func NewNotifier(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) (*Notifier, error) {
 	apiToken := os.Getenv("TELEGRAM_API_KEY")
-	n := &Notifier{}
-	botClient, err := bot.New(apiToken, bot.WithDefaultHandler(n.defaultHandler))
+	mode, err := updatesMode()
+	if err != nil {
+		return nil, err
+	}
+	n := &Notifier{updatesMode: mode}
+	options := []bot.Option{bot.WithDefaultHandler(n.defaultHandler)}
+	if mode == UpdatesModeWebhook {
+		n.webhookSecret = os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+		options = append(options, bot.WithWebhookSecretToken(n.webhookSecret))
+	}
+	botClient, err := bot.New(apiToken, options...)
 	n.bot = botClient
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Telegram bot: %v", err)
 	}
 	n.redisClient = cacheService.RedisClient
 	n.ClickhouseService = clickhouseService
+	n.CacheService = cacheService
 
 	return n, nil
 }
@@ -46,22 +51,61 @@ type Notifier struct {
 	bot               *bot.Bot
 	redisClient       *redis.Client
 	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+	updatesMode       string
+	webhookSecret     string
 }
 
+type AlertKind string
+
+const (
+	AlertKindStatus           AlertKind = "status"
+	AlertKindNetworkDegraded  AlertKind = "network_degraded"
+	AlertKindNetworkRecovered AlertKind = "network_recovered"
+	// AlertKindDegraded is raised when efficiency is still above the
+	// threshold but far below the validator's own baseline.
+	AlertKindDegraded           AlertKind = "degraded"
+	AlertKindDegradationCleared AlertKind = "degradation_cleared"
+	AlertKindIngestionStalled   AlertKind = "ingestion_stalled"
+	AlertKindIngestionRecovered AlertKind = "ingestion_recovered"
+	// Stake alerts are only sent to chats that enabled them with /stake.
+	AlertKindStakeChanged      AlertKind = AlertKind(m.StakeEventChanged)
+	AlertKindElectionsLeft     AlertKind = AlertKind(m.StakeEventLeft)
+	AlertKindElectionsRejoined AlertKind = AlertKind(m.StakeEventRejoined)
+)
+
 type Alert struct {
-	ID                  int64             `json:"id"`
-	ADNLAddr            string            `json:"adnl_addr"`
-	ValidatorADNL       string            `json:"validator_adnl"`
-	Status              m.ValidatorStatus `json:"status"`
-	IsAcknowledged      bool              `json:"is_acknowledged"`
-	AckBy               int64             `json:"ack_by,omitempty"`
-	AckByUsername       string            `json:"ack_by_username,omitempty"`
-	LastAlert           time.Time         `json:"last_alert"`
-	Efficiency          float64           `json:"efficiency"`
-	PreviousStatus      string            `json:"previous_status,omitempty"`
-	PreviousStatusSince time.Time         `json:"previous_status_since,omitempty"`
-	Duration            time.Duration     `json:"duration,omitempty"`
-	Timestamp           uint32            `json:"timestamp,omitempty"`
+	ID                  int64                `json:"id"`
+	Kind                AlertKind            `json:"kind,omitempty"`
+	ADNLAddr            string               `json:"adnl_addr"`
+	ValidatorADNL       string               `json:"validator_adnl"`
+	Status              m.ValidatorStatus    `json:"status"`
+	IsAcknowledged      bool                 `json:"is_acknowledged"`
+	AckBy               int64                `json:"ack_by,omitempty"`
+	AckByUsername       string               `json:"ack_by_username,omitempty"`
+	LastAlert           time.Time            `json:"last_alert"`
+	Efficiency          float64              `json:"efficiency"`
+	PreviousStatus      string               `json:"previous_status,omitempty"`
+	PreviousStatusSince time.Time            `json:"previous_status_since,omitempty"`
+	Duration            time.Duration        `json:"duration,omitempty"`
+	Timestamp           uint32               `json:"timestamp,omitempty"`
+	EstimatedExposure   float64              `json:"estimated_exposure,omitempty"`
+	Network             *m.NetworkHealth     `json:"network,omitempty"`
+	Anomaly             *m.EfficiencyAnomaly `json:"anomaly,omitempty"`
+	Ingestion           *m.IngestionHealth   `json:"ingestion,omitempty"`
+	Stake               *m.StakeChange       `json:"stake,omitempty"`
+}
+
+func (a Alert) isNetworkWide() bool {
+	return a.Kind == AlertKindNetworkDegraded || a.Kind == AlertKindNetworkRecovered
+}
+
+func (a Alert) isForAdmins() bool {
+	return a.Kind == AlertKindIngestionStalled || a.Kind == AlertKindIngestionRecovered
+}
+
+func (a Alert) isOptIn() bool {
+	return a.Kind == AlertKindStakeChanged || a.Kind == AlertKindElectionsLeft || a.Kind == AlertKindElectionsRejoined
 }
 
 type Subscription struct {
@@ -72,6 +116,10 @@ type Subscription struct {
 
 func (n *Notifier) ListenAndNotify(stop <-chan struct{}) {
 	go n.HandleUpdates()
+	go n.RunDigests(stop)
+	go n.RunWalletSync(stop)
+	go n.RunBroadcasts(stop)
+	go n.RunOutbox(stop)
 	subscriber := n.redisClient.Subscribe(ctx, "validator_notifications")
 	msgs := subscriber.Channel()
 
@@ -90,18 +138,33 @@ func (n *Notifier) ListenAndNotify(stop <-chan struct{}) {
 				continue
 			}
 
-			subscriptionKey := fmt.Sprintf("subscription_%s", alert.ValidatorADNL)
-			subscriptions, err := n.redisClient.SMembers(ctx, subscriptionKey).Result()
+			if alert.isNetworkWide() {
+				n.broadcastAlert(alert)
+				continue
+			}
+			if alert.isForAdmins() {
+				messages := n.alertMessages(alert)
+				for _, chatID := range n.adminIDs() {
+					n.sendAlert(chatID, messages, alert)
+				}
+				continue
+			}
+
+			subscriptions, err := n.redisClient.SMembers(ctx, subscriptionKey(alert.ValidatorADNL)).Result()
 			if err != nil {
 				log.Printf("Failed to get subscriptions for ADNLAddr %s: %v", alert.ValidatorADNL, err)
 				continue
 			}
+			if alert.isOptIn() {
+				n.notifyStakeAlert(alert, subscriptions)
+				continue
+			}
 
-			message := n.formatAlertMessage(alert)
+			messages := n.alertMessages(alert)
 			if len(subscriptions) == 0 {
 				defaultUsers := []int64{} // add default users for all notifications
 				for _, chatID := range defaultUsers {
-					n.sendMessage(chatID, message, alert)
+					n.sendAlert(chatID, messages, alert)
 				}
 			} else {
 
@@ -111,9 +174,11 @@ func (n *Notifier) ListenAndNotify(stop <-chan struct{}) {
 						log.Printf("Invalid chat ID: %v", err)
 						continue
 					}
-					n.sendMessage(chatID, message, alert)
+					n.sendAlert(chatID, messages, alert)
 				}
 			}
+
+			n.notifyGroups(alert, messages, subscriptions)
 		case <-stop:
 			log.Println("Notifier is shutting down.")
 			return
@@ -124,73 +189,86 @@ func (n *Notifier) ListenAndNotify(stop <-chan struct{}) {
 	}
 }
 
-func (n *Notifier) formatAlertMessage(alert Alert) string {
-	statusEmoji := "✅"
-	if alert.Status == m.StatusNotOK {
-		statusEmoji = "❌"
-	}
-
-	message := fmt.Sprintf("%s %s\nValidator %s is now %s", statusEmoji, time.Now().Format("2006-01-02 15:04:05"), alert.ValidatorADNL, alert.Status)
-	if alert.PreviousStatus != "" && alert.PreviousStatus != "unknown" {
-		duration := alert.Duration
-		hours := int(duration.Hours())
-		minutes := int(duration.Minutes()) % 60
-		message += fmt.Sprintf("\nPrevious state %s, duration: %dh %d min.", alert.PreviousStatus, hours, minutes)
-	}
-	message += fmt.Sprintf("\n\nCheck details at: https://%s/?adnl=%s&from=%d&to=%d", os.Getenv("HOSTNAME"), alert.ValidatorADNL, alert.Timestamp, alert.Timestamp+uint32(time.Hour.Seconds()))
-
-	return message
-}
-
-func (n *Notifier) sendMessage(chatID int64, message string, alert Alert) {
-	rateLimitKey := fmt.Sprintf("rate_limit_%d_%d", chatID, time.Now().Unix())
-	messageCount, err := n.redisClient.Incr(ctx, rateLimitKey).Result()
+// broadcastAlert sends a network-wide alert once to every subscribed chat.
+func (n *Notifier) broadcastAlert(alert Alert) {
+	subscribers, err := n.redisClient.SMembers(ctx, GlobalSubscriptionKey).Result()
 	if err != nil {
-		log.Printf("Failed to increment rate limit key for chatID %d: %v", chatID, err)
+		log.Printf("Failed to get global subscribers: %v", err)
 		return
 	}
-	if messageCount == 1 {
-		err := n.redisClient.Expire(ctx, rateLimitKey, time.Minute).Err()
+
+	messages := n.alertMessages(alert)
+	for _, chatIDStr := range subscribers {
+		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
 		if err != nil {
-			log.Printf("Failed to set expiration for rate limit key for chatID %d: %v", chatID, err)
+			log.Printf("Invalid chat ID: %v", err)
+			continue
 		}
+		n.sendAlert(chatID, messages, alert)
 	}
+}
 
-	if messageCount > MaxMessagesPerMinute {
-		log.Printf("Rate limit hit for chatID %d, skipping message", chatID)
-		return
-	}
+// sendAlert sends the alert rendered in the chat's language.
+func (n *Notifier) sendAlert(chatID int64, messages *alertMessages, alert Alert) {
+	lang := n.chatLanguage(chatID)
+	n.sendMessage(chatID, lang, messages.get(chatID, lang), alert)
+}
 
-	msg := &bot.SendMessageParams{
-		ChatID: chatID,
-		Text:   message,
-	}
+func detailsURL(alert Alert) string {
+	return fmt.Sprintf("https://%s/?adnl=%s&from=%d&to=%d", os.Getenv("HOSTNAME"), alert.ValidatorADNL, alert.Timestamp, alert.Timestamp+uint32(time.Hour.Seconds()))
+}
 
-	if alert.Status == m.StatusNotOK {
-		ackButton := &models.InlineKeyboardButton{
-			Text:         "Acknowledge",
-			CallbackData: "ack_" + strconv.FormatInt(alert.ID, 10),
-		}
-		msg.ReplyMarkup = &models.InlineKeyboardMarkup{
-			InlineKeyboard: [][]models.InlineKeyboardButton{
-				{*ackButton},
-			},
-		}
-	}
+func formatDuration(lang Language, duration time.Duration) string {
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	return tr(lang, "duration", hours, minutes)
+}
 
-	_, err = n.bot.SendMessage(ctx, msg)
-	if err != nil {
-		log.Printf("Failed to send message to chat %d: %v", chatID, err)
+func formatTime(lang Language, t time.Time) string {
+	if t.IsZero() || t.Unix() <= 0 {
+		return tr(lang, "never")
 	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// sendMessage queues the message for the chat. Alerts that need attention
+// get an acknowledge button.
+func (n *Notifier) sendMessage(chatID int64, lang Language, message Message, alert Alert) {
+	outbound := OutboundMessage{
+		Text:      message.Text,
+		ParseMode: message.ParseMode,
+	}
+	if alert.Status == m.StatusNotOK || alert.Status == m.StatusMissing {
+		outbound.Acks = []AckButton{{
+			AlertID:       alert.ID,
+			ValidatorADNL: alert.ValidatorADNL,
+			Label:         tr(lang, "button.ack"),
+		}}
+	}
+	n.enqueue(chatID, outbound)
 }
 
 func (n *Notifier) HandleUpdates() {
-	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/add", bot.MatchTypePrefix, n.handleAdd)
-	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/del", bot.MatchTypePrefix, n.handleDel)
+	// Handlers are matched in registration order, so longer commands sharing
+	// a prefix with shorter ones have to be registered first.
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/addgroup", bot.MatchTypePrefix, n.handleAddGroup, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/delgroup", bot.MatchTypePrefix, n.handleDelGroup, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/addwallet", bot.MatchTypePrefix, n.handleAddWallet, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/delwallet", bot.MatchTypePrefix, n.handleDelWallet, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/add", bot.MatchTypePrefix, n.handleAdd, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/del", bot.MatchTypePrefix, n.handleDel, n.subscriptionGuard)
 	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/announce", bot.MatchTypePrefix, n.handleAnnounce)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/broadcast", bot.MatchTypePrefix, n.handleBroadcast)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/role", bot.MatchTypePrefix, n.handleRole)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/sla", bot.MatchTypePrefix, n.handleSLA)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/stake", bot.MatchTypePrefix, n.handleStake, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/digest", bot.MatchTypePrefix, n.handleDigest, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/lang", bot.MatchTypePrefix, n.handleLang, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/format", bot.MatchTypePrefix, n.handleFormat, n.subscriptionGuard)
+	n.bot.RegisterHandler(bot.HandlerTypeMessageText, "/clear", bot.MatchTypePrefix, n.handleClear, n.subscriptionGuard)
 	n.bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, "", bot.MatchTypePrefix, n.handleCallback)
 
-	n.bot.Start(ctx)
+	n.receiveUpdates()
 }
 
 func (n *Notifier) handleCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -206,11 +284,7 @@ func (n *Notifier) handleCallback(ctx context.Context, b *bot.Bot, update *model
 		if errors.Is(err, redis.Nil) {
 			if callback.Message.Message != nil {
 				chatID := callback.Message.Message.Chat.ID
-				msg := &bot.SendMessageParams{
-					ChatID: chatID,
-					Text:   "No such alert.",
-				}
-				n.bot.SendMessage(ctx, msg)
+				n.reply(ctx, chatID, tr(n.chatLanguage(chatID), "ack.no_such_alert"))
 			}
 			return
 		}
@@ -222,7 +296,7 @@ func (n *Notifier) handleCallback(ctx context.Context, b *bot.Bot, update *model
 		n.redisClient.Set(ctx, alertKey, alertData, 0)
 		var currentStatus m.ValidatorStatus
 		currentStatus = m.StatusAcknowledged
-		err = n.ClickhouseService.InsertStatusChange(alert.ADNLAddr, alert.ValidatorADNL, currentStatus, time.Now())
+		err = n.ClickhouseService.InsertStatusChange(alert.ADNLAddr, alert.ValidatorADNL, currentStatus, time.Now(), n.CacheService)
 		if err != nil {
 			log.Printf("Failed to insert status change into ClickHouse: %v", err)
 		}
@@ -238,7 +312,7 @@ func (n *Notifier) handleCallback(ctx context.Context, b *bot.Bot, update *model
 
 			msg := &bot.SendMessageParams{
 				ChatID:    chatID,
-				Text:      fmt.Sprintf("[%s] 🚑 Acknowledged by [%s](tg://user?id\\=%d)", time.Now().Format("2006\\-01\\-02 15:04:05"), userName, callback.From.ID),
+				Text:      fmt.Sprintf("[%s] 🚑 %s [%s](tg://user?id\\=%d)", time.Now().Format("2006\\-01\\-02 15:04:05"), escapeMarkdownV2(tr(n.chatLanguage(chatID), "ack.done")), escapeMarkdownV2(userName), callback.From.ID),
 				ParseMode: models.ParseModeMarkdown,
 			}
 			_, err := n.bot.SendMessage(ctx, msg)
@@ -256,236 +330,192 @@ func (n *Notifier) handleAdd(ctx context.Context, b *bot.Bot, update *models.Upd
 	}
 
 	chatID := update.Message.Chat.ID
-	args := strings.Split(update.Message.Text, " ")
-	if len(args) < 2 {
-		msg := &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   "Usage: /add <ADNL>",
-		}
-		n.bot.SendMessage(ctx, msg)
+	lang := n.messageLanguage(update.Message)
+	adnls, invalid := parseADNLs(commandArgs(update.Message.Text))
+	if len(adnls) == 0 && len(invalid) == 0 {
+		n.reply(ctx, chatID, tr(lang, "add.usage"))
 		return
 	}
-
-	adnl := args[1]
-	adnlPattern := `^[A-F0-9]{64}$`
-	matched, err := regexp.MatchString(adnlPattern, adnl)
-	if err != nil || !matched {
-		msg := &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   "Invalid ADNL format. ADNL must be a 64-character hex string (uppercase, A-F, 0-9).",
-		}
-		_, err := n.bot.SendMessage(ctx, msg)
-		if err != nil {
-			log.Printf("Failed to send message to chat %d: %v", chatID, err)
+	if len(invalid) > 0 {
+		n.reply(ctx, chatID, tr(lang, "add.invalid")+"\n"+strings.Join(invalid, "\n"))
+		if len(adnls) == 0 {
 			return
 		}
-		return
 	}
 
-	subscriptionKey := fmt.Sprintf("subscription_%s", adnl)
-
-	err = n.redisClient.SAdd(ctx, subscriptionKey, chatID).Err()
+	wallets, err := n.chatWallets(chatID)
 	if err != nil {
-		log.Printf("Failed to add subscription for ADNL %s: %v", adnl, err)
-		msg := &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   "Failed to subscribe to alerts.",
+		log.Printf("Failed to get wallets of chat %d: %v", chatID, err)
+	}
+
+	var added, failed []string
+	for _, adnl := range adnls {
+		if err := n.subscribe(chatID, subscriptionKey(adnl)); err != nil {
+			log.Printf("Failed to add subscription for ADNL %s: %v", adnl, err)
+			failed = append(failed, adnl)
+			continue
 		}
-		_, err := n.bot.SendMessage(ctx, msg)
+		added = append(added, adnl)
+		// Added explicitly, so it stays when a wallet holding it is removed.
+		for _, wallet := range wallets {
+			if err := n.redisClient.SRem(ctx, walletValidatorsKey(chatID, wallet), adnl).Err(); err != nil {
+				log.Printf("Failed to detach ADNL %s from wallet %s: %v", adnl, wallet, err)
+			}
+		}
+	}
+
+	if len(added) > 0 {
+		err := n.redisClient.SAdd(ctx, GlobalSubscriptionKey, chatID).Err()
 		if err != nil {
-			log.Printf("Failed to send message to chat %d: %v", chatID, err)
-			return
+			log.Printf("Failed to add to global subscribers list: %v", err)
 		}
+		n.reply(ctx, chatID, tr(lang, "add.done", strings.Join(added, "\n")))
+	}
+	if len(failed) > 0 {
+		n.reply(ctx, chatID, tr(lang, "add.failed")+"\n"+strings.Join(failed, "\n"))
+	}
+}
+
+func (n *Notifier) handleDel(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
 		return
 	}
 
-	err = n.redisClient.SAdd(ctx, GlobalSubscriptionKey, chatID).Err()
-	if err != nil {
-		log.Printf("Failed to add to global subscribers list: %v", err)
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	adnls := commandArgs(update.Message.Text)
+	if len(adnls) == 0 {
+		n.reply(ctx, chatID, tr(lang, "del.usage"))
+		return
 	}
 
-	msg := &bot.SendMessageParams{
-		ChatID: chatID,
-		Text:   fmt.Sprintf("Subscribed to alerts for ADNL: %s", adnl),
+	var removed, failed []string
+	for _, adnl := range adnls {
+		if err := n.unsubscribe(chatID, subscriptionKey(adnl)); err != nil {
+			log.Printf("Failed to remove subscription for ADNL %s: %v", adnl, err)
+			failed = append(failed, adnl)
+			continue
+		}
+		removed = append(removed, adnl)
 	}
-	_, err = n.bot.SendMessage(ctx, msg)
-	if err != nil {
-		log.Printf("Failed to send message to chat %d: %v", chatID, err)
-		return
+
+	n.removeIfUnsubscribed(chatID)
+
+	if len(removed) > 0 {
+		n.reply(ctx, chatID, tr(lang, "del.done", strings.Join(removed, "\n")))
+	}
+	if len(failed) > 0 {
+		n.reply(ctx, chatID, tr(lang, "del.failed")+"\n"+strings.Join(failed, "\n"))
 	}
 }
 
-func (n *Notifier) handleDel(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (n *Notifier) handleSLA(ctx context.Context, b *bot.Bot, update *models.Update) {
 	if update.Message == nil || update.Message.Text == "" {
 		return
 	}
 
 	chatID := update.Message.Chat.ID
-	args := strings.Split(update.Message.Text, " ")
+	lang := n.messageLanguage(update.Message)
+	args := strings.Fields(update.Message.Text)
 	if len(args) < 2 {
-		msg := &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   "Usage: /del <ADNL>",
-		}
-		_, err := n.bot.SendMessage(ctx, msg)
-		if err != nil {
-			log.Printf("Failed to send message to chat %d: %v", chatID, err)
-			return
-		}
+		n.reply(ctx, chatID, tr(lang, "sla.usage"))
 		return
 	}
 
 	adnl := args[1]
-	subscriptionKey := fmt.Sprintf("subscription_%s", adnl)
-
-	err := n.redisClient.SRem(ctx, subscriptionKey, chatID).Err()
-	if err != nil {
-		log.Printf("Failed to remove subscription for ADNL %s: %v", adnl, err)
-		msg := &bot.SendMessageParams{
-			ChatID: chatID,
-			Text:   "Failed to unsubscribe from alerts.",
-		}
-		_, err := n.bot.SendMessage(ctx, msg)
-		if err != nil {
-			log.Printf("Failed to send message to chat %d: %v", chatID, err)
+	days := 7
+	if len(args) > 2 {
+		parsed, err := strconv.Atoi(args[2])
+		if err != nil || parsed <= 0 || parsed > 365 {
+			n.reply(ctx, chatID, tr(lang, "sla.invalid_days"))
 			return
 		}
-		return
+		days = parsed
 	}
 
-	subscriptions, err := n.redisClient.Keys(ctx, "subscription_*").Result()
+	to := time.Now()
+	from := to.Add(-time.Duration(days) * 24 * time.Hour)
+	sla, err := n.ClickhouseService.GetValidatorSLA(adnl, from, to, n.CacheService)
 	if err != nil {
-		log.Printf("Failed to check subscriptions for chat %d: %v", chatID, err)
+		log.Printf("Failed to compute SLA for ADNL %s: %v", adnl, err)
+		n.reply(ctx, chatID, tr(lang, "sla.failed"))
 		return
 	}
 
-	isSubscribedToOtherADNL := false
-	for _, subKey := range subscriptions {
-		isMember, err := n.redisClient.SIsMember(ctx, subKey, chatID).Result()
-		if err == nil && isMember {
-			isSubscribedToOtherADNL = true
-			break
-		}
+	text := tr(lang, "sla.report", adnl, days, sla.OKPercent, sla.NotOKPercent, sla.AcknowledgedPercent, sla.UnknownPercent, sla.Incidents)
+	if sla.MTTRSeconds > 0 {
+		text += "\n" + tr(lang, "sla.mttr", formatDuration(lang, time.Duration(sla.MTTRSeconds)*time.Second))
 	}
-
-	if !isSubscribedToOtherADNL {
-		err := n.redisClient.SRem(ctx, GlobalSubscriptionKey, chatID).Err()
-		if err != nil {
-			log.Printf("Failed to remove from global subscribers list: %v", err)
-		}
+	if sla.MTBFSeconds > 0 {
+		text += "\n" + tr(lang, "sla.mtbf", formatDuration(lang, time.Duration(sla.MTBFSeconds)*time.Second))
 	}
 
-	msg := &bot.SendMessageParams{
-		ChatID: chatID,
-		Text:   fmt.Sprintf("Unsubscribed from alerts for ADNL: %s", adnl),
-	}
-	_, err = n.bot.SendMessage(ctx, msg)
-	if err != nil {
-		log.Printf("Failed to send message to chat %d: %v", chatID, err)
-		return
-	}
+	n.reply(ctx, chatID, text)
 }
 
-func (n *Notifier) handleAnnounce(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (n *Notifier) handleLang(ctx context.Context, b *bot.Bot, update *models.Update) {
 	if update.Message == nil || update.Message.Text == "" {
 		return
 	}
 
-	if !n.isAdmin(update.Message.Chat.ID) {
-		msg := &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   "You are not authorized to use this command.",
-		}
-		_, err := n.bot.SendMessage(ctx, msg)
-		if err != nil {
-			log.Printf("Failed to send message to chat %d: %v", update.Message.Chat.ID, err)
-			return
-		}
-		return
-	}
-
-	args := strings.SplitN(update.Message.Text, " ", 2)
-	if len(args) < 2 {
-		msg := &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   "Usage: /announce <message>",
-		}
-		_, err := n.bot.SendMessage(ctx, msg)
-		if err != nil {
-			log.Printf("Failed to send message to chat %d: %v", update.Message.Chat.ID, err)
-			return
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	args := strings.Fields(update.Message.Text)
+	var chosen Language
+	ok := false
+	if len(args) > 1 {
+		chosen, ok = parseLanguage(args[1])
+	}
+	if !ok {
+		codes := make([]string, len(supportedLanguages))
+		for i, l := range supportedLanguages {
+			codes[i] = string(l)
 		}
+		n.reply(ctx, chatID, tr(lang, "lang.usage", strings.Join(codes, "|")))
 		return
 	}
 
-	announcement := args[1]
-
-	subscribers, err := n.redisClient.SMembers(ctx, GlobalSubscriptionKey).Result()
+	err := n.redisClient.HSet(ctx, chatLanguageKey, strconv.FormatInt(chatID, 10), string(chosen)).Err()
 	if err != nil {
-		log.Printf("Failed to get global subscribers: %v", err)
-		msg := &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   "Failed to send announcement.",
-		}
-		_, err := n.bot.SendMessage(ctx, msg)
-		if err != nil {
-			log.Printf("Failed to send message to chat %d: %v", update.Message.Chat.ID, err)
-			return
-		}
+		log.Printf("Failed to save language for chat %d: %v", chatID, err)
+		n.reply(ctx, chatID, tr(lang, "lang.failed"))
 		return
 	}
 
-	for _, chatIDStr := range subscribers {
-		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
-		if err != nil {
-			log.Printf("Invalid chat ID: %v", err)
-			continue
-		}
-
-		msg := &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      fmt.Sprintf("📢 Announcement:\n\n%s", announcement),
-			ParseMode: "Markdown",
-		}
+	n.reply(ctx, chatID, tr(chosen, "lang.done"))
+}
 
-		_, err = n.bot.SendMessage(ctx, msg)
-		if err != nil {
-			log.Printf("Failed to send message to chat %d: %v", chatID, err)
-			return
-		}
+// getChatSubscriptions returns the ADNLs the chat is subscribed to.
+func (n *Notifier) getChatSubscriptions(chatID int64) ([]string, error) {
+	keys, err := n.redisClient.SMembers(ctx, chatSubscriptionsKey(chatID)).Result()
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("Announcement sent to %d subscribers.", len(subscribers))
-}
-
-func (n *Notifier) isAdmin(chatID int64) bool {
-	for _, adminID := range adminChatIDs {
-		if chatID == adminID {
-			return true
+	var adnls []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, "subscription_") {
+			adnls = append(adnls, strings.TrimPrefix(key, "subscription_"))
 		}
 	}
-	return false
+	sort.Strings(adnls)
+	return adnls, nil
 }
 
 func (n *Notifier) defaultHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	if update.Message != nil && strings.HasPrefix(update.Message.Text, "/start") {
 		args := strings.Split(update.Message.Text, " ")
+		// Deep links carry several ADNLs separated by "-" or "_":
+		// /start add_<ADNL>-<ADNL>.
 		if len(args) > 1 && strings.HasPrefix(args[1], "add_") {
-			adnl := strings.TrimPrefix(args[1], "add_")
-			update.Message.Text = "/add " + adnl
-			n.handleAdd(ctx, b, update)
+			adnls := strings.FieldsFunc(strings.TrimPrefix(args[1], "add_"), func(r rune) bool { return r == '-' || r == '_' })
+			update.Message.Text = "/add " + strings.Join(adnls, " ")
+			n.subscriptionGuard(n.handleAdd)(ctx, b, update)
 		}
 	}
 
 	if update.Message != nil {
-		msg := &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   "Unknown command. Available commands:\n/add <ADNL> - Subscribe to alerts\n/del <ADNL> - Unsubscribe from alerts",
-		}
-		_, err := b.SendMessage(ctx, msg)
-		if err != nil {
-			return
-		}
+		n.reply(ctx, update.Message.Chat.ID, tr(n.messageLanguage(update.Message), "help"))
 	}
 }
 
@@ -0,0 +1,458 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// Alerts and digests are not sent right away but queued per chat in Redis
+// and delivered by RunOutbox within Telegram's limits: about 30 messages per
+// second overall, one per second to a private chat and 20 per minute to a
+// group. Messages that pile up for a chat are merged into one.
+const (
+	outboxChatsKey = "outbox_chats"
+
+	outboxTickInterval  = 100 * time.Millisecond
+	privateChatInterval = time.Second
+	groupChatInterval   = 3 * time.Second
+	maxMessageLength    = 4096
+	maxOutboundAttempts = 5
+	// outboxSendTimeout bounds a single send; the chat stays locked for
+	// outboxSendLockTTL so the lock can't expire while the send is running.
+	outboxSendTimeout    = 20 * time.Second
+	outboxSendLockTTL    = 30 * time.Second
+	outboxDeadLettersKey = "outbox_dead_letters"
+	maxDeadLetters       = 1000
+	// telegramPauseKey is set while Telegram asks the bot to slow down. Its
+	// retry_after applies to the whole bot, so every send waits for it.
+	telegramPauseKey = "telegram_paused"
+)
+
+// OutboundMessage is a message waiting in a chat's outbox.
+type OutboundMessage struct {
+	Text      string           `json:"text"`
+	ParseMode models.ParseMode `json:"parse_mode,omitempty"`
+	Acks      []AckButton      `json:"acks,omitempty"`
+	Attempts  int              `json:"attempts,omitempty"`
+	// Single keeps the message from being merged with others, after a
+	// merged message containing it was rejected.
+	Single bool `json:"single,omitempty"`
+}
+
+// deadLetter is a message Telegram rejected, kept for inspection.
+type deadLetter struct {
+	ChatID  int64           `json:"chat_id"`
+	Message OutboundMessage `json:"message"`
+	Error   string          `json:"error"`
+	Time    time.Time       `json:"time"`
+}
+
+// AckButton acknowledges the alert it belongs to.
+type AckButton struct {
+	AlertID       int64  `json:"alert_id"`
+	ValidatorADNL string `json:"validator_adnl"`
+	Label         string `json:"label"`
+}
+
+func outboxKey(chatID int64) string {
+	return fmt.Sprintf("outbox:%d", chatID)
+}
+
+// outboxProcessingKey holds the messages being sent to the chat until
+// Telegram accepts them.
+func outboxProcessingKey(chatID int64) string {
+	return fmt.Sprintf("outbox_processing:%d", chatID)
+}
+
+func outboxPaceKey(chatID int64) string {
+	return fmt.Sprintf("outbox_pace:%d", chatID)
+}
+
+// globalMessagesPerSecond is the number of messages all replicas together
+// may send per second.
+func globalMessagesPerSecond() int64 {
+	rate, err := strconv.ParseInt(os.Getenv("TELEGRAM_MESSAGES_PER_SECOND"), 10, 64)
+	if err != nil || rate <= 0 {
+		return 25
+	}
+	return rate
+}
+
+// chatInterval is the minimal gap between two messages to the chat. Group
+// and channel IDs are negative.
+func chatInterval(chatID int64) time.Duration {
+	if chatID < 0 {
+		return groupChatInterval
+	}
+	return privateChatInterval
+}
+
+// enqueue adds the message to the end of the chat's outbox.
+func (n *Notifier) enqueue(chatID int64, message OutboundMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Failed to serialize message for chat %d: %v", chatID, err)
+		return
+	}
+	if err := n.redisClient.RPush(ctx, outboxKey(chatID), data).Err(); err != nil {
+		log.Printf("Failed to queue message for chat %d: %v", chatID, err)
+		return
+	}
+	if err := n.redisClient.SAdd(ctx, outboxChatsKey, chatID).Err(); err != nil {
+		log.Printf("Failed to register outbox of chat %d: %v", chatID, err)
+	}
+}
+
+// acquireGlobalSlot reserves one message of the current second's global
+// budget.
+func (n *Notifier) acquireGlobalSlot() bool {
+	return n.acquireRateSlot("outbox_rate", globalMessagesPerSecond())
+}
+
+// acquireRateSlot reserves one of the limit messages all replicas together
+// may send in the current second under the prefix.
+func (n *Notifier) acquireRateSlot(prefix string, limit int64) bool {
+	key := fmt.Sprintf("%s:%d", prefix, time.Now().Unix())
+	count, err := n.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("Failed to increment %s rate limit: %v", prefix, err)
+		return false
+	}
+	if count == 1 {
+		n.redisClient.Expire(ctx, key, 2*time.Second)
+	}
+	return count <= limit
+}
+
+// pauseSending holds back all sends of every replica for the duration.
+func (n *Notifier) pauseSending(d time.Duration) {
+	if err := n.redisClient.Set(ctx, telegramPauseKey, 1, d).Err(); err != nil {
+		log.Printf("Failed to pause sending: %v", err)
+	}
+}
+
+func (n *Notifier) sendingPaused() bool {
+	paused, err := n.redisClient.Exists(ctx, telegramPauseKey).Result()
+	if err != nil {
+		log.Printf("Failed to check whether sending is paused: %v", err)
+		return false
+	}
+	return paused > 0
+}
+
+// RunOutbox delivers queued messages until stopped.
+func (n *Notifier) RunOutbox(stop <-chan struct{}) {
+	ticker := time.NewTicker(outboxTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			n.flushOutboxes()
+		}
+	}
+}
+
+func (n *Notifier) flushOutboxes() {
+	if n.sendingPaused() {
+		return
+	}
+	chats, err := n.redisClient.SMembers(ctx, outboxChatsKey).Result()
+	if err != nil {
+		log.Printf("Failed to get outboxes: %v", err)
+		return
+	}
+
+	for _, chatIDStr := range chats {
+		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+		if err != nil {
+			n.redisClient.SRem(ctx, outboxChatsKey, chatIDStr)
+			continue
+		}
+
+		pending, err := n.pendingMessages(chatID)
+		if err != nil {
+			continue
+		}
+		if pending == 0 {
+			n.redisClient.SRem(ctx, outboxChatsKey, chatIDStr)
+			// A message may have been queued in between.
+			if pending, err := n.pendingMessages(chatID); err == nil && pending > 0 {
+				n.redisClient.SAdd(ctx, outboxChatsKey, chatIDStr)
+			}
+			continue
+		}
+
+		// The pace key both spaces out messages to the chat and keeps other
+		// replicas from sending to it at the same time. It is held for the
+		// whole send and then set to the chat's interval.
+		acquired, err := n.redisClient.SetNX(ctx, outboxPaceKey(chatID), 1, outboxSendLockTTL).Result()
+		if err != nil || !acquired {
+			continue
+		}
+		if !n.acquireGlobalSlot() {
+			n.redisClient.Del(ctx, outboxPaceKey(chatID))
+			return
+		}
+		n.flushOutbox(chatID)
+	}
+}
+
+// flushOutbox sends the messages waiting for the chat, merged into one. The
+// messages are moved to a processing list first and only dropped from it
+// once Telegram accepted them, so a replica stopping mid-send loses nothing.
+func (n *Notifier) flushOutbox(chatID int64) {
+	key, processingKey := outboxKey(chatID), outboxProcessingKey(chatID)
+
+	// Messages left by a send that never finished go first.
+	for {
+		err := n.redisClient.RPopLPush(ctx, processingKey, key).Err()
+		if errors.Is(err, redis.Nil) {
+			break
+		}
+		if err != nil {
+			log.Printf("Failed to restore outbox of chat %d: %v", chatID, err)
+			n.pace(chatID, chatInterval(chatID))
+			return
+		}
+	}
+
+	items, err := n.redisClient.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		log.Printf("Failed to read outbox of chat %d: %v", chatID, err)
+		n.pace(chatID, chatInterval(chatID))
+		return
+	}
+
+	var batch []OutboundMessage
+	taken := 0
+	for _, item := range items {
+		var message OutboundMessage
+		if err := json.Unmarshal([]byte(item), &message); err != nil {
+			log.Printf("Dropping malformed message for chat %d: %v", chatID, err)
+			taken++
+			continue
+		}
+		if len(batch) > 0 && !canCoalesce(batch, message) {
+			break
+		}
+		batch = append(batch, message)
+		taken++
+	}
+	// Only this replica holds the chat, and new messages are appended at
+	// the other end, so the first taken items are the ones read above.
+	for i := 0; i < taken; i++ {
+		if err := n.redisClient.LMove(ctx, key, processingKey, "LEFT", "RIGHT").Err(); err != nil {
+			log.Printf("Failed to take messages from outbox of chat %d: %v", chatID, err)
+			n.pace(chatID, chatInterval(chatID))
+			return
+		}
+	}
+	if len(batch) == 0 {
+		n.redisClient.Del(ctx, processingKey)
+		n.pace(chatID, chatInterval(chatID))
+		return
+	}
+
+	err = n.deliver(chatID, coalesce(batch))
+
+	var tooManyRequests *bot.TooManyRequestsError
+	switch {
+	case err == nil:
+		n.redisClient.Del(ctx, processingKey)
+		n.pace(chatID, chatInterval(chatID))
+	case errors.As(err, &tooManyRequests):
+		n.requeue(chatID, batch)
+		retryAfter := time.Duration(tooManyRequests.RetryAfter) * time.Second
+		n.pace(chatID, retryAfter)
+		n.pauseSending(retryAfter)
+		log.Printf("Telegram asked to retry after %s (chat %d)", retryAfter, chatID)
+	case errors.Is(err, bot.ErrorForbidden):
+		log.Printf("Bot was blocked in chat %d, dropping its outbox and subscriptions", chatID)
+		n.redisClient.Del(ctx, processingKey, key)
+		if err := n.removeChat(chatID); err != nil {
+			log.Printf("Failed to remove blocked chat %d: %v", chatID, err)
+		}
+	case errors.Is(err, bot.ErrorBadRequest) && len(batch) > 1:
+		// One of the merged messages is at fault; retry each one alone so
+		// only that one is dropped.
+		log.Printf("Telegram rejected merged message to chat %d, retrying %d messages separately: %v", chatID, len(batch), err)
+		for i := range batch {
+			batch[i].Single = true
+		}
+		n.requeue(chatID, batch)
+		n.pace(chatID, chatInterval(chatID))
+	case errors.Is(err, bot.ErrorBadRequest):
+		log.Printf("Telegram rejected message to chat %d: %v", chatID, err)
+		n.deadLetter(chatID, batch[0], err)
+		n.redisClient.Del(ctx, processingKey)
+		n.pace(chatID, chatInterval(chatID))
+	default:
+		log.Printf("Failed to send message to chat %d: %v", chatID, err)
+		attempts := 0
+		var retry []OutboundMessage
+		for _, message := range batch {
+			message.Attempts++
+			if message.Attempts > attempts {
+				attempts = message.Attempts
+			}
+			if message.Attempts >= maxOutboundAttempts {
+				log.Printf("Giving up on message to chat %d after %d attempts", chatID, message.Attempts)
+				n.deadLetter(chatID, message, err)
+				continue
+			}
+			retry = append(retry, message)
+		}
+		n.requeue(chatID, retry)
+		// 2s after the first failure, doubling with every further one.
+		n.pace(chatID, time.Duration(1<<uint(attempts))*time.Second)
+	}
+}
+
+// pendingMessages counts the chat's queued messages, including ones of a
+// send that never finished.
+func (n *Notifier) pendingMessages(chatID int64) (int64, error) {
+	pipe := n.redisClient.Pipeline()
+	queued := pipe.LLen(ctx, outboxKey(chatID))
+	processing := pipe.LLen(ctx, outboxProcessingKey(chatID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return queued.Val() + processing.Val(), nil
+}
+
+// pace keeps other sends to the chat back for the duration.
+func (n *Notifier) pace(chatID int64, d time.Duration) {
+	if err := n.redisClient.Set(ctx, outboxPaceKey(chatID), 1, d).Err(); err != nil {
+		log.Printf("Failed to pace chat %d: %v", chatID, err)
+	}
+}
+
+// requeue puts the messages back in front of the chat's outbox in order and
+// clears the chat's processing list in the same transaction.
+func (n *Notifier) requeue(chatID int64, messages []OutboundMessage) {
+	pipe := n.redisClient.TxPipeline()
+	for i := len(messages) - 1; i >= 0; i-- {
+		data, err := json.Marshal(messages[i])
+		if err != nil {
+			continue
+		}
+		pipe.LPush(ctx, outboxKey(chatID), data)
+	}
+	pipe.Del(ctx, outboxProcessingKey(chatID))
+	pipe.SAdd(ctx, outboxChatsKey, chatID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to requeue messages for chat %d: %v", chatID, err)
+	}
+}
+
+// deadLetter keeps a message that won't be sent in a capped Redis list.
+func (n *Notifier) deadLetter(chatID int64, message OutboundMessage, cause error) {
+	data, err := json.Marshal(deadLetter{ChatID: chatID, Message: message, Error: cause.Error(), Time: time.Now()})
+	if err != nil {
+		return
+	}
+	pipe := n.redisClient.TxPipeline()
+	pipe.LPush(ctx, outboxDeadLettersKey, data)
+	pipe.LTrim(ctx, outboxDeadLettersKey, 0, maxDeadLetters-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to store dead letter for chat %d: %v", chatID, err)
+	}
+}
+
+// utf16Length is the length of s as Telegram counts it, in UTF-16 code
+// units.
+func utf16Length(s string) int {
+	length := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			length += 2
+		} else {
+			length++
+		}
+	}
+	return length
+}
+
+// canCoalesce reports whether the message can be appended to the batch
+// without exceeding Telegram's message length.
+func canCoalesce(batch []OutboundMessage, message OutboundMessage) bool {
+	if message.Single || batch[0].Single || message.ParseMode != batch[0].ParseMode {
+		return false
+	}
+	length := utf16Length(message.Text)
+	for _, queued := range batch {
+		length += utf16Length(queued.Text) + utf16Length(messageSeparator(queued.ParseMode))
+	}
+	return length <= maxMessageLength
+}
+
+func messageSeparator(parseMode models.ParseMode) string {
+	if parseMode == models.ParseModeMarkdown {
+		return "\n\n" + escapeMarkdownV2("———") + "\n\n"
+	}
+	return "\n\n———\n\n"
+}
+
+// coalesce merges the messages into one. When it carries several ack
+// buttons, each of them names its validator.
+func coalesce(batch []OutboundMessage) OutboundMessage {
+	if len(batch) == 1 {
+		return batch[0]
+	}
+
+	texts := make([]string, len(batch))
+	merged := OutboundMessage{ParseMode: batch[0].ParseMode}
+	for i, message := range batch {
+		texts[i] = message.Text
+		merged.Acks = append(merged.Acks, message.Acks...)
+	}
+	merged.Text = strings.Join(texts, messageSeparator(merged.ParseMode))
+	if len(merged.Acks) > 1 {
+		for i, ack := range merged.Acks {
+			adnl := ack.ValidatorADNL
+			if len(adnl) > 8 {
+				adnl = adnl[:8] + "…"
+			}
+			merged.Acks[i].Label = fmt.Sprintf("%s %s", ack.Label, adnl)
+		}
+	}
+	return merged
+}
+
+func (n *Notifier) deliver(chatID int64, message OutboundMessage) error {
+	msg := &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      message.Text,
+		ParseMode: message.ParseMode,
+	}
+
+	if len(message.Acks) > 0 {
+		var keyboard [][]models.InlineKeyboardButton
+		for _, ack := range message.Acks {
+			keyboard = append(keyboard, []models.InlineKeyboardButton{{
+				Text:         ack.Label,
+				CallbackData: "ack_" + strconv.FormatInt(ack.AlertID, 10),
+			}})
+		}
+		msg.ReplyMarkup = &models.InlineKeyboardMarkup{InlineKeyboard: keyboard}
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	defer cancel()
+	_, err := n.bot.SendMessage(sendCtx, msg)
+	return err
+}
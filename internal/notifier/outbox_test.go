@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestCanCoalesce(t *testing.T) {
+	// The plain separator is 7 runes long.
+	separator := len([]rune(messageSeparator("")))
+
+	tests := []struct {
+		name    string
+		batch   []OutboundMessage
+		message OutboundMessage
+		want    bool
+	}{
+		{
+			name:    "short messages",
+			batch:   []OutboundMessage{{Text: "first"}},
+			message: OutboundMessage{Text: "second"},
+			want:    true,
+		},
+		{
+			name:    "different parse modes",
+			batch:   []OutboundMessage{{Text: "first", ParseMode: models.ParseModeMarkdown}},
+			message: OutboundMessage{Text: "second"},
+		},
+		{
+			name:    "single message",
+			batch:   []OutboundMessage{{Text: "first"}},
+			message: OutboundMessage{Text: "second", Single: true},
+		},
+		{
+			name:    "single batch",
+			batch:   []OutboundMessage{{Text: "first", Single: true}},
+			message: OutboundMessage{Text: "second"},
+		},
+		{
+			name:    "exactly the limit",
+			batch:   []OutboundMessage{{Text: strings.Repeat("a", 4000)}},
+			message: OutboundMessage{Text: strings.Repeat("b", maxMessageLength-4000-separator)},
+			want:    true,
+		},
+		{
+			name:    "over the limit",
+			batch:   []OutboundMessage{{Text: strings.Repeat("a", 4000)}},
+			message: OutboundMessage{Text: strings.Repeat("b", maxMessageLength-4000-separator+1)},
+		},
+		{
+			name:    "length counts UTF-16 units",
+			batch:   []OutboundMessage{{Text: strings.Repeat("😀", 2000)}},
+			message: OutboundMessage{Text: strings.Repeat("b", 90)},
+		},
+		{
+			name:    "letters outside ASCII count once",
+			batch:   []OutboundMessage{{Text: strings.Repeat("я", 3000)}},
+			message: OutboundMessage{Text: "second"},
+			want:    true,
+		},
+		{
+			name:    "separators of the whole batch count",
+			batch:   []OutboundMessage{{Text: strings.Repeat("a", 2000)}, {Text: strings.Repeat("a", 2000)}},
+			message: OutboundMessage{Text: strings.Repeat("b", maxMessageLength-4000-2*separator+1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canCoalesce(tt.batch, tt.message); got != tt.want {
+				t.Errorf("canCoalesce() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
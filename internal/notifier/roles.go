@@ -0,0 +1,233 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	m "validators-health/internal/models"
+)
+
+// Role is a Telegram user's role in the bot. Owners are configured with
+// BOT_OWNER_IDS; admins and members are stored in Redis.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+
+	rolesKey = "bot_roles"
+)
+
+// ownerIDs returns the user IDs listed in BOT_OWNER_IDS, separated by commas.
+func ownerIDs() []int64 {
+	var ids []int64
+	for _, field := range strings.Split(os.Getenv("BOT_OWNER_IDS"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			log.Printf("Invalid owner ID %q in BOT_OWNER_IDS", field)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// membersOnly restricts subscriptions to users with a role when
+// BOT_MEMBERS_ONLY is true.
+func membersOnly() bool {
+	value, _ := strconv.ParseBool(os.Getenv("BOT_MEMBERS_ONLY"))
+	return value
+}
+
+// roleOf returns the user's role, or an empty role if the user has none.
+func (n *Notifier) roleOf(userID int64) Role {
+	for _, id := range ownerIDs() {
+		if id == userID {
+			return RoleOwner
+		}
+	}
+	role, err := n.redisClient.HGet(ctx, rolesKey, strconv.FormatInt(userID, 10)).Result()
+	if err != nil {
+		return ""
+	}
+	return Role(role)
+}
+
+func (n *Notifier) isAdmin(userID int64) bool {
+	role := n.roleOf(userID)
+	return role == RoleOwner || role == RoleAdmin
+}
+
+// adminIDs returns owners and admins. Their user IDs double as the IDs of
+// their private chats with the bot.
+func (n *Notifier) adminIDs() []int64 {
+	ids := ownerIDs()
+	roles, err := n.redisClient.HGetAll(ctx, rolesKey).Result()
+	if err != nil {
+		log.Printf("Failed to get bot roles: %v", err)
+		return ids
+	}
+	for userIDStr, role := range roles {
+		if Role(role) != RoleAdmin {
+			continue
+		}
+		userID, err := strconv.ParseInt(userIDStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, userID)
+	}
+	return ids
+}
+
+// audit records an administrative action taken with the message.
+func (n *Notifier) audit(message *models.Message, action, details string) {
+	entry := m.AuditLogEntry{
+		Timestamp: time.Now(),
+		ChatID:    message.Chat.ID,
+		Action:    action,
+		Details:   details,
+	}
+	if message.From != nil {
+		entry.UserID = message.From.ID
+		entry.Username = message.From.Username
+	}
+	if err := n.ClickhouseService.InsertAuditLog(entry); err != nil {
+		log.Printf("Failed to write audit log for %s: %v", action, err)
+	}
+}
+
+// subscriptionGuard only lets chat administrators change the subscriptions
+// of a group chat and, with BOT_MEMBERS_ONLY, only users with a role change
+// any subscriptions.
+func (n *Notifier) subscriptionGuard(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		message := update.Message
+		// Without a sender, e.g. in channel posts, nobody can be checked.
+		if message == nil || message.From == nil {
+			return
+		}
+
+		userID := message.From.ID
+		if n.isAdmin(userID) {
+			next(ctx, b, update)
+			return
+		}
+
+		lang := n.messageLanguage(message)
+		if membersOnly() && n.roleOf(userID) == "" {
+			n.reply(ctx, message.Chat.ID, tr(lang, "role.members_only"))
+			return
+		}
+
+		if message.Chat.Type == models.ChatTypeGroup || message.Chat.Type == models.ChatTypeSupergroup {
+			member, err := b.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: message.Chat.ID, UserID: userID})
+			if err != nil {
+				log.Printf("Failed to get member %d of chat %d: %v", userID, message.Chat.ID, err)
+				n.reply(ctx, message.Chat.ID, tr(lang, "role.chat_admins_only"))
+				return
+			}
+			if member.Type != models.ChatMemberTypeOwner && member.Type != models.ChatMemberTypeAdministrator {
+				n.reply(ctx, message.Chat.ID, tr(lang, "role.chat_admins_only"))
+				return
+			}
+		}
+
+		next(ctx, b, update)
+	}
+}
+
+// handleRole lists roles with /role and changes them with
+// /role <user_id> <admin|member|none>. Owners manage admins and members,
+// admins manage members.
+func (n *Notifier) handleRole(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	callerRole := n.roleOf(update.Message.From.ID)
+	if callerRole != RoleOwner && callerRole != RoleAdmin {
+		n.reply(ctx, chatID, tr(lang, "announce.unauthorized"))
+		return
+	}
+
+	args := commandArgs(update.Message.Text)
+	if len(args) == 0 {
+		n.reply(ctx, chatID, n.formatRoles(lang))
+		return
+	}
+	if len(args) != 2 {
+		n.reply(ctx, chatID, tr(lang, "role.usage"))
+		return
+	}
+
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		n.reply(ctx, chatID, tr(lang, "role.usage"))
+		return
+	}
+	newRole := Role(args[1])
+	if newRole != RoleAdmin && newRole != RoleMember && newRole != "none" {
+		n.reply(ctx, chatID, tr(lang, "role.usage"))
+		return
+	}
+
+	currentRole := n.roleOf(userID)
+	if currentRole == RoleOwner ||
+		(callerRole == RoleAdmin && (newRole == RoleAdmin || currentRole == RoleAdmin)) {
+		n.reply(ctx, chatID, tr(lang, "role.forbidden"))
+		return
+	}
+
+	userKey := strconv.FormatInt(userID, 10)
+	if newRole == "none" {
+		err = n.redisClient.HDel(ctx, rolesKey, userKey).Err()
+	} else {
+		err = n.redisClient.HSet(ctx, rolesKey, userKey, string(newRole)).Err()
+	}
+	if err != nil {
+		log.Printf("Failed to set role of user %d: %v", userID, err)
+		n.reply(ctx, chatID, tr(lang, "role.failed"))
+		return
+	}
+
+	n.audit(update.Message, "role", fmt.Sprintf("user %d: %s -> %s", userID, currentRole, newRole))
+	n.reply(ctx, chatID, tr(lang, "role.done", userID, newRole))
+}
+
+func (n *Notifier) formatRoles(lang Language) string {
+	var lines []string
+	for _, id := range ownerIDs() {
+		lines = append(lines, fmt.Sprintf("%d: %s", id, RoleOwner))
+	}
+
+	roles, err := n.redisClient.HGetAll(ctx, rolesKey).Result()
+	if err != nil {
+		log.Printf("Failed to get bot roles: %v", err)
+	}
+	var users []string
+	for userID := range roles {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+	for _, userID := range users {
+		lines = append(lines, fmt.Sprintf("%s: %s", userID, roles[userID]))
+	}
+
+	return tr(lang, "role.list") + "\n" + strings.Join(lines, "\n") + "\n\n" + tr(lang, "role.usage")
+}
@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// stakeAlertsKey holds the chats that opted in to stake alerts.
+const stakeAlertsKey = "stake_alerts_chats"
+
+// notifyStakeAlert sends a stake alert to the chats that follow the
+// validator, directly or through a group, and enabled stake alerts.
+func (n *Notifier) notifyStakeAlert(alert Alert, subscriptions []string) {
+	recipients := make(map[string]bool)
+	for _, chatIDStr := range subscriptions {
+		recipients[chatIDStr] = true
+	}
+	groups, err := n.ClickhouseService.GetGroupsOf(alert.ADNLAddr, n.CacheService)
+	if err != nil {
+		log.Printf("Failed to get groups for ADNL %s: %v", alert.ADNLAddr, err)
+	}
+	for _, group := range groups {
+		members, err := n.redisClient.SMembers(ctx, groupSubscriptionKey(group.Name)).Result()
+		if err != nil {
+			log.Printf("Failed to get subscriptions for group %s: %v", group.Name, err)
+			continue
+		}
+		for _, chatIDStr := range members {
+			recipients[chatIDStr] = true
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	optedIn, err := n.redisClient.SMembers(ctx, stakeAlertsKey).Result()
+	if err != nil {
+		log.Printf("Failed to get stake alert chats: %v", err)
+		return
+	}
+
+	messages := n.alertMessages(alert)
+	for _, chatIDStr := range optedIn {
+		if !recipients[chatIDStr] {
+			continue
+		}
+		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+		if err != nil {
+			log.Printf("Invalid chat ID: %v", err)
+			continue
+		}
+		n.sendAlert(chatID, messages, alert)
+	}
+}
+
+func (n *Notifier) handleStake(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	args := commandArgs(update.Message.Text)
+
+	if len(args) == 0 {
+		enabled, err := n.redisClient.SIsMember(ctx, stakeAlertsKey, chatID).Result()
+		if err != nil {
+			log.Printf("Failed to get stake alert setting of chat %d: %v", chatID, err)
+			n.reply(ctx, chatID, tr(lang, "stake.failed"))
+			return
+		}
+		state := tr(lang, "stake.off")
+		if enabled {
+			state = tr(lang, "stake.on")
+		}
+		n.reply(ctx, chatID, state+"\n"+tr(lang, "stake.usage"))
+		return
+	}
+
+	var err error
+	switch args[0] {
+	case "on":
+		err = n.redisClient.SAdd(ctx, stakeAlertsKey, chatID).Err()
+	case "off":
+		err = n.redisClient.SRem(ctx, stakeAlertsKey, chatID).Err()
+	default:
+		n.reply(ctx, chatID, tr(lang, "stake.usage"))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update stake alert setting of chat %d: %v", chatID, err)
+		n.reply(ctx, chatID, tr(lang, "stake.failed"))
+		return
+	}
+
+	if args[0] == "on" {
+		n.reply(ctx, chatID, tr(lang, "stake.enabled"))
+	} else {
+		n.reply(ctx, chatID, tr(lang, "stake.disabled"))
+	}
+}
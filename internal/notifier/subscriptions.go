@@ -0,0 +1,447 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const walletSyncInterval = 5 * time.Minute
+
+var adnlPattern = regexp.MustCompile(`^[A-F0-9]{64}$`)
+
+// subscriptionIndexedKey marks that the chat subscription index was built
+// from the subscription sets that existed before it.
+const subscriptionIndexedKey = "chat_subscriptions_indexed"
+
+func subscriptionKey(adnl string) string {
+	return fmt.Sprintf("subscription_%s", adnl)
+}
+
+func walletSubscriptionKey(wallet string) string {
+	return fmt.Sprintf("wallet_subscription_%s", wallet)
+}
+
+// chatSubscriptionsKey holds the keys of every subscription set the chat is
+// a member of, so its subscriptions are found without scanning all keys.
+func chatSubscriptionsKey(chatID int64) string {
+	return fmt.Sprintf("chat_subscriptions:%d", chatID)
+}
+
+// walletValidatorsKey holds the validator ADNLs the chat is subscribed to
+// because they belong to the wallet, as opposed to ones added with /add.
+func walletValidatorsKey(chatID int64, wallet string) string {
+	return fmt.Sprintf("wallet_validators:%d:%s", chatID, wallet)
+}
+
+// commandArgs returns the arguments of a bot command, separated by spaces,
+// new lines or commas.
+func commandArgs(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == '\n' || r == '\t' || r == ','
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields[1:]
+}
+
+// parseADNLs splits the arguments into valid, deduplicated ADNLs and the
+// ones that are not.
+func parseADNLs(args []string) (adnls []string, invalid []string) {
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if !adnlPattern.MatchString(arg) {
+			invalid = append(invalid, arg)
+			continue
+		}
+		if !seen[arg] {
+			seen[arg] = true
+			adnls = append(adnls, arg)
+		}
+	}
+	return adnls, invalid
+}
+
+// subscribe adds the chat to the subscription set and to its index.
+func (n *Notifier) subscribe(chatID int64, key string) error {
+	pipe := n.redisClient.TxPipeline()
+	pipe.SAdd(ctx, key, chatID)
+	pipe.SAdd(ctx, chatSubscriptionsKey(chatID), key)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// unsubscribe removes the chat from the subscription set and from its index.
+func (n *Notifier) unsubscribe(chatID int64, key string) error {
+	pipe := n.redisClient.TxPipeline()
+	pipe.SRem(ctx, key, chatID)
+	pipe.SRem(ctx, chatSubscriptionsKey(chatID), key)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// chatWallets returns the wallets the chat follows.
+func (n *Notifier) chatWallets(chatID int64) ([]string, error) {
+	keys, err := n.redisClient.SMembers(ctx, chatSubscriptionsKey(chatID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	var wallets []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, "wallet_subscription_") {
+			wallets = append(wallets, strings.TrimPrefix(key, "wallet_subscription_"))
+		}
+	}
+	return wallets, nil
+}
+
+// heldByOtherWallet reports whether the chat is subscribed to the validator
+// through a wallet other than the given one.
+func (n *Notifier) heldByOtherWallet(chatID int64, wallet, adnl string) (bool, error) {
+	wallets, err := n.chatWallets(chatID)
+	if err != nil {
+		return false, err
+	}
+	for _, other := range wallets {
+		if other == wallet {
+			continue
+		}
+		isMember, err := n.redisClient.SIsMember(ctx, walletValidatorsKey(chatID, other), adnl).Result()
+		if err != nil {
+			return false, err
+		}
+		if isMember {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// releaseWalletValidator unsubscribes the chat from a validator it followed
+// through the wallet, unless another wallet still holds it.
+func (n *Notifier) releaseWalletValidator(chatID int64, wallet, adnl string) error {
+	if err := n.redisClient.SRem(ctx, walletValidatorsKey(chatID, wallet), adnl).Err(); err != nil {
+		return err
+	}
+	held, err := n.heldByOtherWallet(chatID, wallet, adnl)
+	if err != nil || held {
+		return err
+	}
+	return n.unsubscribe(chatID, subscriptionKey(adnl))
+}
+
+func (n *Notifier) handleAddWallet(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	args := commandArgs(update.Message.Text)
+	if len(args) != 1 {
+		n.reply(ctx, chatID, tr(lang, "addwallet.usage"))
+		return
+	}
+	wallet := args[0]
+
+	err := n.subscribe(chatID, walletSubscriptionKey(wallet))
+	if err != nil {
+		log.Printf("Failed to add subscription for wallet %s: %v", wallet, err)
+		n.reply(ctx, chatID, tr(lang, "addwallet.failed"))
+		return
+	}
+
+	err = n.redisClient.SAdd(ctx, GlobalSubscriptionKey, chatID).Err()
+	if err != nil {
+		log.Printf("Failed to add to global subscribers list: %v", err)
+	}
+
+	validators, err := n.syncWalletSubscription(wallet)
+	if err != nil {
+		log.Printf("Failed to subscribe to validators of wallet %s: %v", wallet, err)
+		n.reply(ctx, chatID, tr(lang, "addwallet.failed"))
+		return
+	}
+
+	n.reply(ctx, chatID, tr(lang, "addwallet.done", validators, wallet))
+}
+
+func (n *Notifier) handleDelWallet(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	args := commandArgs(update.Message.Text)
+	if len(args) != 1 {
+		n.reply(ctx, chatID, tr(lang, "delwallet.usage"))
+		return
+	}
+	wallet := args[0]
+
+	err := n.unsubscribe(chatID, walletSubscriptionKey(wallet))
+	if err != nil {
+		log.Printf("Failed to remove subscription for wallet %s: %v", wallet, err)
+		n.reply(ctx, chatID, tr(lang, "delwallet.failed"))
+		return
+	}
+
+	// Only the validators subscribed through the wallet are removed; ones
+	// the chat added with /add stay.
+	adnls, err := n.redisClient.SMembers(ctx, walletValidatorsKey(chatID, wallet)).Result()
+	if err != nil {
+		log.Printf("Failed to get validators of wallet %s for chat %d: %v", wallet, chatID, err)
+		n.reply(ctx, chatID, tr(lang, "delwallet.failed"))
+		return
+	}
+	for _, adnl := range adnls {
+		if err := n.releaseWalletValidator(chatID, wallet, adnl); err != nil {
+			log.Printf("Failed to remove subscription for ADNL %s: %v", adnl, err)
+			n.reply(ctx, chatID, tr(lang, "delwallet.failed"))
+			return
+		}
+	}
+
+	n.removeIfUnsubscribed(chatID)
+
+	n.reply(ctx, chatID, tr(lang, "delwallet.done", wallet))
+}
+
+// handleClear removes every ADNL, group and wallet subscription of the chat.
+func (n *Notifier) handleClear(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := n.messageLanguage(update.Message)
+	if err := n.removeChat(chatID); err != nil {
+		log.Printf("Failed to remove subscriptions of chat %d: %v", chatID, err)
+		n.reply(ctx, chatID, tr(lang, "clear.failed"))
+		return
+	}
+
+	n.reply(ctx, chatID, tr(lang, "clear.done"))
+}
+
+// removeChat removes the chat from every subscription and from the global
+// subscribers.
+func (n *Notifier) removeChat(chatID int64) error {
+	keys, err := n.redisClient.SMembers(ctx, chatSubscriptionsKey(chatID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := n.redisClient.SRem(ctx, key, chatID).Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(key, "wallet_subscription_") {
+			wallet := strings.TrimPrefix(key, "wallet_subscription_")
+			if err := n.redisClient.Del(ctx, walletValidatorsKey(chatID, wallet)).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := n.redisClient.Del(ctx, chatSubscriptionsKey(chatID)).Err(); err != nil {
+		return err
+	}
+	return n.redisClient.SRem(ctx, GlobalSubscriptionKey, chatID).Err()
+}
+
+// removeIfUnsubscribed drops the chat from the global subscribers once it
+// has no ADNL, group or wallet subscriptions left.
+func (n *Notifier) removeIfUnsubscribed(chatID int64) {
+	count, err := n.redisClient.SCard(ctx, chatSubscriptionsKey(chatID)).Result()
+	if err != nil {
+		log.Printf("Failed to check subscriptions for chat %d: %v", chatID, err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	err = n.redisClient.SRem(ctx, GlobalSubscriptionKey, chatID).Err()
+	if err != nil {
+		log.Printf("Failed to remove from global subscribers list: %v", err)
+	}
+}
+
+// scanKeys returns the keys matching the pattern without blocking Redis
+// the way KEYS does.
+func (n *Notifier) scanKeys(pattern string) ([]string, error) {
+	var keys []string
+	iter := n.redisClient.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// indexSubscriptions builds the chat subscription index from the
+// subscription sets once, for data written before the index existed.
+func (n *Notifier) indexSubscriptions() error {
+	indexed, err := n.redisClient.Exists(ctx, subscriptionIndexedKey).Result()
+	if err != nil || indexed > 0 {
+		return err
+	}
+
+	for _, pattern := range []string{"subscription_*", "group_subscription_*", "wallet_subscription_*"} {
+		keys, err := n.scanKeys(pattern)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			chats, err := n.redisClient.SMembers(ctx, key).Result()
+			if err != nil {
+				return err
+			}
+			for _, chatIDStr := range chats {
+				chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+				if err != nil {
+					continue
+				}
+				if err := n.redisClient.SAdd(ctx, chatSubscriptionsKey(chatID), key).Err(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return n.redisClient.Set(ctx, subscriptionIndexedKey, time.Now().Unix(), 0).Err()
+}
+
+// RunWalletSync keeps the followers of a wallet subscribed to exactly the
+// wallet's validators of the latest cycle, until stopped.
+func (n *Notifier) RunWalletSync(stop <-chan struct{}) {
+	if err := n.indexSubscriptions(); err != nil {
+		log.Printf("Failed to index chat subscriptions: %v", err)
+	}
+
+	ticker := time.NewTicker(walletSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			keys, err := n.scanKeys("wallet_subscription_*")
+			if err != nil {
+				log.Printf("Failed to list wallet subscriptions: %v", err)
+				continue
+			}
+			for _, key := range keys {
+				wallet := strings.TrimPrefix(key, "wallet_subscription_")
+				if _, err := n.syncWalletSubscription(wallet); err != nil {
+					log.Printf("Failed to sync subscriptions of wallet %s: %v", wallet, err)
+				}
+			}
+		}
+	}
+}
+
+// syncWalletSubscription subscribes the wallet's followers to each of its
+// validators in the latest cycle, unsubscribes them from validators that
+// left it and returns the number of validators. Alerts are routed by the
+// validator ADNL, so the wallet's ADNL addresses are resolved to it first.
+func (n *Notifier) syncWalletSubscription(wallet string) (int, error) {
+	followers, err := n.redisClient.SMembers(ctx, walletSubscriptionKey(wallet)).Result()
+	if err != nil {
+		return 0, err
+	}
+	group, err := n.ClickhouseService.GetWalletGroup(wallet, n.CacheService)
+	if err != nil {
+		return 0, err
+	}
+
+	var validators []string
+	if group != nil {
+		resolved, err := n.ClickhouseService.GetValidatorADNLs(group.Members)
+		if err != nil {
+			return 0, err
+		}
+		seen := make(map[string]bool, len(resolved))
+		for _, validatorADNL := range resolved {
+			if !seen[validatorADNL] {
+				seen[validatorADNL] = true
+				validators = append(validators, validatorADNL)
+			}
+		}
+	}
+
+	for _, chatIDStr := range followers {
+		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+		if err != nil {
+			log.Printf("Invalid chat ID: %v", err)
+			continue
+		}
+		if err := n.syncChatWallet(chatID, wallet, validators); err != nil {
+			return 0, err
+		}
+	}
+	return len(validators), nil
+}
+
+// syncChatWallet brings the chat's subscriptions through the wallet in line
+// with the wallet's current validators.
+func (n *Notifier) syncChatWallet(chatID int64, wallet string, validators []string) error {
+	key := walletValidatorsKey(chatID, wallet)
+	held, err := n.redisClient.SMembers(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	heldSet := make(map[string]bool, len(held))
+	for _, adnl := range held {
+		heldSet[adnl] = true
+	}
+	current := make(map[string]bool, len(validators))
+	for _, adnl := range validators {
+		current[adnl] = true
+	}
+
+	for _, adnl := range validators {
+		// A validator removed with /del stays in the set, so it isn't
+		// subscribed again.
+		if heldSet[adnl] {
+			continue
+		}
+		subscribed, err := n.redisClient.SIsMember(ctx, subscriptionKey(adnl), chatID).Result()
+		if err != nil {
+			return err
+		}
+		if subscribed {
+			// Subscribed with /add: leave it to the chat.
+			otherWallet, err := n.heldByOtherWallet(chatID, wallet, adnl)
+			if err != nil {
+				return err
+			}
+			if !otherWallet {
+				continue
+			}
+		}
+		if err := n.subscribe(chatID, subscriptionKey(adnl)); err != nil {
+			return err
+		}
+		if err := n.redisClient.SAdd(ctx, key, adnl).Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, adnl := range held {
+		if current[adnl] {
+			continue
+		}
+		if err := n.releaseWalletValidator(chatID, wallet, adnl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
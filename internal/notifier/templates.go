@@ -0,0 +1,536 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	m "validators-health/internal/models"
+)
+
+// chatFormatKey maps chat IDs to the message format chosen with /format.
+const chatFormatKey = "chat_message_format"
+
+// MessageFormat is the markup a template is written in and rendered for.
+type MessageFormat string
+
+const (
+	FormatPlain      MessageFormat = "plain"
+	FormatMarkdownV2 MessageFormat = "markdownv2"
+	FormatHTML       MessageFormat = "html"
+)
+
+func (f MessageFormat) parseMode() models.ParseMode {
+	switch f {
+	case FormatMarkdownV2:
+		return models.ParseModeMarkdown
+	case FormatHTML:
+		return models.ParseModeHTML
+	}
+	return ""
+}
+
+// escape makes a value safe to embed into a message of the format.
+func (f MessageFormat) escape(s string) string {
+	switch f {
+	case FormatMarkdownV2:
+		return escapeMarkdownV2(s)
+	case FormatHTML:
+		return html.EscapeString(s)
+	}
+	return s
+}
+
+var markdownV2Replacer = func() *strings.Replacer {
+	var pairs []string
+	for _, c := range `\_*[]()~` + "`" + `>#+-=|{}.!` {
+		pairs = append(pairs, string(c), `\`+string(c))
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+func escapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+// Message is a rendered message ready to be sent.
+type Message struct {
+	Text      string
+	ParseMode models.ParseMode
+}
+
+// WithLine appends a plain text paragraph, escaped for the message's parse mode.
+func (msg Message) WithLine(line string) Message {
+	format := FormatPlain
+	switch msg.ParseMode {
+	case models.ParseModeMarkdown:
+		format = FormatMarkdownV2
+	case models.ParseModeHTML:
+		format = FormatHTML
+	}
+	msg.Text += "\n\n" + format.escape(line)
+	return msg
+}
+
+// AlertTemplateData is the data model available to alert templates.
+type AlertTemplateData struct {
+	// Kind is the alert kind, e.g. "status" or "degraded".
+	Kind AlertKind
+	// Language is the language of the chat: "en", "ru" or "zh".
+	Language Language
+	// Time is the moment the message is rendered, "2006-01-02 15:04:05".
+	Time string
+	// StatusEmoji is a symbol matching Status.
+	StatusEmoji string
+	// Status is the new validator status: "ok", "not ok" or "missing";
+	// use the status function for its localized name.
+	Status m.ValidatorStatus
+	// PreviousStatus is empty when the previous status was unknown.
+	PreviousStatus string
+	// Duration is how long the previous state lasted.
+	Duration      time.Duration
+	ADNLAddr      string
+	ValidatorADNL string
+	// Efficiency is the scoreboard efficiency in percent that caused the alert.
+	Efficiency float64
+	// EstimatedExposure is the expected fine in TON for NOT OK and missing alerts.
+	EstimatedExposure float64
+	DetailsURL        string
+	// Validator holds the validator's data from its latest cycle; it is nil
+	// for network-wide and admin alerts or when the validator is unknown.
+	Validator *m.ValidatorInfo
+	Network   *m.NetworkHealth
+	Anomaly   *m.EfficiencyAnomaly
+	Ingestion *m.IngestionHealth
+	// Stake describes stake_changed, elections_left and elections_rejoined alerts.
+	Stake *m.StakeChange
+}
+
+// templateFuncs are available in every template. Values produced by an
+// action are escaped for the template's format unless the action ends with
+// a call to raw.
+var templateFuncs = template.FuncMap{
+	// ton converts nanoTON to TON.
+	"ton": func(nano int64) string { return fmt.Sprintf("%.2f", float64(nano)/1e9) },
+	// seconds converts a number of seconds into a time.Duration.
+	"seconds": func(s int64) time.Duration { return time.Duration(s) * time.Second },
+	"raw":     func(s string) string { return s },
+}
+
+// localeFuncs are the template functions that depend on the language of the
+// chat a message is rendered for.
+func localeFuncs(lang Language) template.FuncMap {
+	return template.FuncMap{
+		// tr formats the catalog message with the key.
+		"tr": func(key string, args ...interface{}) string { return tr(lang, key, args...) },
+		// status returns the localized name of a validator status.
+		"status":         func(status interface{}) string { return statusName(lang, fmt.Sprint(status)) },
+		"formatDuration": func(d time.Duration) string { return formatDuration(lang, d) },
+		"formatTime":     func(t time.Time) string { return formatTime(lang, t) },
+	}
+}
+
+var builtinTemplates = map[AlertKind]string{
+	AlertKindStatus: `{{.StatusEmoji}} {{.Time}}
+{{tr "alert.now" .ValidatorADNL (status .Status)}}
+{{- if .PreviousStatus}}
+{{tr "alert.previous" (status .PreviousStatus) (formatDuration .Duration)}}
+{{- end}}
+{{- if eq .Status "missing"}}
+{{tr "alert.missing"}}
+{{- else}}
+{{tr "alert.efficiency" (printf "%.2f" .Efficiency)}}
+{{- end}}
+{{- if .EstimatedExposure}}
+{{tr "alert.exposure" (printf "%.2f" .EstimatedExposure)}}
+{{- end}}
+
+{{tr "alert.details" .DetailsURL}}`,
+
+	AlertKindNetworkDegraded: `⚠️ {{.Time}}
+{{tr "alert.network_degraded" .Network.CycleID (printf "%.1f" .Network.NotOKWeightPercent) .Network.NotOKValidators .Network.Validators}}
+{{tr "alert.network_held"}}`,
+
+	AlertKindNetworkRecovered: `✅ {{.Time}}
+{{tr "alert.network_recovered" .Network.CycleID (formatDuration .Duration) (printf "%.1f" .Network.NotOKWeightPercent) .Network.NotOKValidators .Network.Validators}}
+{{tr "alert.network_resumed"}}`,
+
+	AlertKindDegraded: `⚠️ {{.Time}}
+{{tr "alert.degraded" .ValidatorADNL}}
+{{tr "alert.degraded_evidence" (printf "%.2f" .Anomaly.Efficiency) (printf "%.2f" .Anomaly.Baseline) (printf "%.2f" .Anomaly.MAD) (printf "%.1f" .Anomaly.Score) .Anomaly.Samples .Anomaly.BaselineHours}}
+
+{{tr "alert.details" .DetailsURL}}`,
+
+	AlertKindDegradationCleared: `✅ {{.Time}}
+{{tr "alert.degradation_cleared" .ValidatorADNL (printf "%.2f" .Efficiency) (formatDuration .Duration)}}
+
+{{tr "alert.details" .DetailsURL}}`,
+
+	AlertKindIngestionStalled: `🚨 {{.Time}}
+{{tr "alert.ingestion_stalled" (formatDuration (seconds .Ingestion.LagThresholdSeconds))}}
+{{tr "alert.last_sample" (formatTime .Ingestion.LastSampleAt)}}
+{{tr "alert.last_cycles_fetch" (formatTime .Ingestion.LastCyclesFetchAt)}}`,
+
+	AlertKindIngestionRecovered: `✅ {{.Time}}
+{{tr "alert.ingestion_resumed" (formatDuration .Duration)}}
+{{tr "alert.last_sample" (formatTime .Ingestion.LastSampleAt)}}
+{{tr "alert.last_cycles_fetch" (formatTime .Ingestion.LastCyclesFetchAt)}}`,
+
+	AlertKindStakeChanged: `💰 {{.Time}}
+{{tr "alert.stake_changed" .ValidatorADNL (ton .Stake.PreviousStake) (ton .Stake.Stake) (printf "%+.1f" .Stake.ChangePercent) .Stake.PreviousCycleID .Stake.CycleID}}
+
+{{tr "alert.details" .DetailsURL}}`,
+
+	AlertKindElectionsLeft: `🚪 {{.Time}}
+{{tr "alert.elections_left" .ValidatorADNL .Stake.MissedCycles .Stake.PreviousCycleID (ton .Stake.PreviousStake)}}`,
+
+	AlertKindElectionsRejoined: `🔁 {{.Time}}
+{{tr "alert.elections_rejoined" .ValidatorADNL .Stake.CycleID (ton .Stake.Stake) .Stake.MissedCycles}}
+
+{{tr "alert.details" .DetailsURL}}`,
+}
+
+// templateSet holds custom templates by format, kind and language; the
+// empty language holds the template used for languages without their own.
+type templateSet map[MessageFormat]map[AlertKind]map[Language]*template.Template
+
+func (set templateSet) lookup(format MessageFormat, kind AlertKind, lang Language) *template.Template {
+	if tmpl := set[format][kind][lang]; tmpl != nil {
+		return tmpl
+	}
+	return set[format][kind][""]
+}
+
+// alertTemplates holds the compiled built-in templates and the custom ones
+// loaded from ALERT_TEMPLATES_DIR, named "<kind>.<format>.tmpl" or
+// "<kind>.<format>.<lang>.tmpl" for a single language. Templates in the
+// "chat_<id>" subdirectory override the global ones for that chat.
+type alertTemplates struct {
+	// format is the default format of chats that haven't chosen one.
+	format  MessageFormat
+	builtin map[AlertKind]*template.Template
+	global  templateSet
+	chats   map[int64]templateSet
+}
+
+var (
+	templatesOnce     sync.Once
+	templatesInstance *alertTemplates
+)
+
+// parseMessageFormat returns the format with the name, if it is known.
+func parseMessageFormat(name string) (MessageFormat, bool) {
+	switch format := MessageFormat(strings.ToLower(name)); format {
+	case FormatPlain, FormatMarkdownV2, FormatHTML:
+		return format, true
+	}
+	return "", false
+}
+
+func getAlertTemplates() *alertTemplates {
+	templatesOnce.Do(func() {
+		format, ok := parseMessageFormat(os.Getenv("ALERT_MESSAGE_FORMAT"))
+		if !ok {
+			format = FormatPlain
+		}
+
+		templatesInstance = &alertTemplates{
+			format:  format,
+			builtin: make(map[AlertKind]*template.Template),
+			chats:   make(map[int64]templateSet),
+		}
+		for kind, text := range builtinTemplates {
+			templatesInstance.builtin[kind] = template.Must(compileTemplate(string(kind), text, FormatPlain))
+		}
+
+		dir := os.Getenv("ALERT_TEMPLATES_DIR")
+		if dir == "" {
+			return
+		}
+		templatesInstance.global = loadTemplateSet(dir)
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			log.Printf("Failed to read alert templates directory %s: %v", dir, err)
+			return
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "chat_") {
+				continue
+			}
+			chatID, err := strconv.ParseInt(strings.TrimPrefix(entry.Name(), "chat_"), 10, 64)
+			if err != nil {
+				log.Printf("Invalid chat templates directory %s", entry.Name())
+				continue
+			}
+			templatesInstance.chats[chatID] = loadTemplateSet(filepath.Join(dir, entry.Name()))
+		}
+	})
+	return templatesInstance
+}
+
+// loadTemplateSet loads the custom templates of every format from the directory.
+func loadTemplateSet(dir string) templateSet {
+	set := make(templateSet)
+	languages := append([]Language{""}, supportedLanguages...)
+	for _, format := range []MessageFormat{FormatPlain, FormatMarkdownV2, FormatHTML} {
+		for kind := range builtinTemplates {
+			for _, lang := range languages {
+				name := fmt.Sprintf("%s.%s.tmpl", kind, format)
+				if lang != "" {
+					name = fmt.Sprintf("%s.%s.%s.tmpl", kind, format, lang)
+				}
+				path := filepath.Join(dir, name)
+				text, err := os.ReadFile(path)
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					log.Printf("Failed to read alert template %s: %v", path, err)
+					continue
+				}
+				tmpl, err := compileTemplate(string(kind), string(text), format)
+				if err != nil {
+					log.Printf("Failed to parse alert template %s: %v", path, err)
+					continue
+				}
+				if set[format] == nil {
+					set[format] = make(map[AlertKind]map[Language]*template.Template)
+				}
+				if set[format][kind] == nil {
+					set[format][kind] = make(map[Language]*template.Template)
+				}
+				set[format][kind][lang] = tmpl
+				log.Printf("Loaded alert template %s", path)
+			}
+		}
+	}
+	return set
+}
+
+// compileTemplate parses the template and makes every action escape its
+// output for the format.
+func compileTemplate(name, text string, format MessageFormat) (*template.Template, error) {
+	funcs := template.FuncMap{"escape": func(v interface{}) string { return format.escape(fmt.Sprint(v)) }}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Funcs(localeFuncs(defaultLanguage)).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			addEscaping(t.Tree, t.Tree.Root)
+		}
+	}
+	return tmpl, nil
+}
+
+func addEscaping(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addEscaping(tree, child)
+		}
+	case *parse.ActionNode:
+		// Assignments like {{$x := ...}} produce no output.
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "raw" {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Args:     []parse.Node{parse.NewIdentifier("escape").SetTree(tree).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		addEscaping(tree, n.List)
+		addEscaping(tree, n.ElseList)
+	case *parse.RangeNode:
+		addEscaping(tree, n.List)
+		addEscaping(tree, n.ElseList)
+	case *parse.WithNode:
+		addEscaping(tree, n.List)
+		addEscaping(tree, n.ElseList)
+	}
+}
+
+// render uses the chat's custom template for the alert kind, format and
+// language if there is one, then the global one, and the built-in plain text
+// template otherwise.
+func (t *alertTemplates) render(data AlertTemplateData, chatID int64, format MessageFormat) (Message, error) {
+	kind := data.Kind
+	if kind == "" {
+		kind = AlertKindStatus
+	}
+
+	tmpl := t.chats[chatID].lookup(format, kind, data.Language)
+	if tmpl == nil {
+		tmpl = t.global.lookup(format, kind, data.Language)
+	}
+	if tmpl == nil {
+		tmpl, format = t.builtin[kind], FormatPlain
+	}
+	if tmpl == nil {
+		return Message{}, fmt.Errorf("no template for alert kind %q", kind)
+	}
+
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return Message{}, err
+	}
+	tmpl.Funcs(localeFuncs(data.Language))
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return Message{}, err
+	}
+	return Message{Text: buf.String(), ParseMode: format.parseMode()}, nil
+}
+
+// alertMessages renders an alert at most once per language and format it is
+// sent in; chats with their own templates get their own rendering.
+type alertMessages struct {
+	n        *Notifier
+	data     AlertTemplateData
+	rendered map[renderKey]Message
+}
+
+type renderKey struct {
+	chatID int64
+	format MessageFormat
+	lang   Language
+}
+
+func (n *Notifier) alertMessages(alert Alert) *alertMessages {
+	return &alertMessages{
+		n:        n,
+		data:     n.alertTemplateData(alert),
+		rendered: make(map[renderKey]Message),
+	}
+}
+
+// get returns the alert rendered for the chat in the language.
+func (a *alertMessages) get(chatID int64, lang Language) Message {
+	templates := getAlertTemplates()
+	key := renderKey{format: a.n.chatFormat(chatID), lang: lang}
+	if _, ok := templates.chats[chatID]; ok {
+		key.chatID = chatID
+	}
+	if message, ok := a.rendered[key]; ok {
+		return message
+	}
+
+	data := a.data
+	data.Language = lang
+	message, err := templates.render(data, key.chatID, key.format)
+	if err != nil {
+		log.Printf("Failed to render %s alert for validator %s: %v", data.Kind, data.ValidatorADNL, err)
+		message = Message{Text: fmt.Sprintf("Alert for validator %s: %s", data.ValidatorADNL, data.Status)}
+	}
+	a.rendered[key] = message
+	return message
+}
+
+// chatFormat returns the message format chosen for the chat with /format,
+// or ALERT_MESSAGE_FORMAT.
+func (n *Notifier) chatFormat(chatID int64) MessageFormat {
+	name, err := n.redisClient.HGet(ctx, chatFormatKey, strconv.FormatInt(chatID, 10)).Result()
+	if err == nil {
+		if format, ok := parseMessageFormat(name); ok {
+			return format
+		}
+	}
+	return getAlertTemplates().format
+}
+
+func (n *Notifier) handleFormat(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	chatKey := strconv.FormatInt(chatID, 10)
+	lang := n.messageLanguage(update.Message)
+	args := commandArgs(update.Message.Text)
+	if len(args) != 1 {
+		n.reply(ctx, chatID, tr(lang, "format.usage", n.chatFormat(chatID)))
+		return
+	}
+
+	var err error
+	if args[0] == "default" {
+		err = n.redisClient.HDel(ctx, chatFormatKey, chatKey).Err()
+	} else if format, ok := parseMessageFormat(args[0]); ok {
+		err = n.redisClient.HSet(ctx, chatFormatKey, chatKey, string(format)).Err()
+	} else {
+		n.reply(ctx, chatID, tr(lang, "format.usage", n.chatFormat(chatID)))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to save message format for chat %d: %v", chatID, err)
+		n.reply(ctx, chatID, tr(lang, "format.failed"))
+		return
+	}
+	n.reply(ctx, chatID, tr(lang, "format.done", n.chatFormat(chatID)))
+}
+
+func (n *Notifier) alertTemplateData(alert Alert) AlertTemplateData {
+	data := AlertTemplateData{
+		Kind:          alert.Kind,
+		Language:      defaultLanguage,
+		Time:          time.Now().Format("2006-01-02 15:04:05"),
+		StatusEmoji:   "✅",
+		Status:        alert.Status,
+		Duration:      alert.Duration,
+		ADNLAddr:      alert.ADNLAddr,
+		ValidatorADNL: alert.ValidatorADNL,
+		Efficiency:    alert.Efficiency,
+		DetailsURL:    detailsURL(alert),
+		Network:       alert.Network,
+		Anomaly:       alert.Anomaly,
+		Ingestion:     alert.Ingestion,
+		Stake:         alert.Stake,
+	}
+	if data.Kind == "" {
+		data.Kind = AlertKindStatus
+	}
+
+	switch alert.Status {
+	case m.StatusNotOK:
+		data.StatusEmoji = "❌"
+	case m.StatusMissing:
+		data.StatusEmoji = "❓"
+	}
+	if alert.PreviousStatus != string(m.StatusUnknown) {
+		data.PreviousStatus = alert.PreviousStatus
+	}
+	if alert.Status == m.StatusNotOK || alert.Status == m.StatusMissing {
+		data.EstimatedExposure = alert.EstimatedExposure
+	}
+
+	if alert.ADNLAddr != "" {
+		info, err := n.ClickhouseService.GetValidatorInfo(alert.ADNLAddr, n.CacheService)
+		if err != nil {
+			log.Printf("Failed to get validator info for %s: %v", alert.ADNLAddr, err)
+		}
+		data.Validator = info
+	}
+
+	return data
+}
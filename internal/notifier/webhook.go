@@ -0,0 +1,73 @@
+package notifier
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-telegram/bot"
+)
+
+// TELEGRAM_UPDATES_MODE chooses how the bot receives updates. Long polling
+// only works with a single consumer, so deployments with several replicas
+// should use a webhook, which Telegram delivers to just one of them.
+const (
+	UpdatesModePolling = "polling"
+	UpdatesModeWebhook = "webhook"
+
+	WebhookPath = "/api/telegram/webhook"
+)
+
+func updatesMode() (string, error) {
+	mode := strings.ToLower(os.Getenv("TELEGRAM_UPDATES_MODE"))
+	switch mode {
+	case "", UpdatesModePolling:
+		return UpdatesModePolling, nil
+	case UpdatesModeWebhook:
+		if os.Getenv("TELEGRAM_WEBHOOK_URL") == "" || os.Getenv("TELEGRAM_WEBHOOK_SECRET") == "" {
+			return "", fmt.Errorf("webhook mode requires TELEGRAM_WEBHOOK_URL and TELEGRAM_WEBHOOK_SECRET")
+		}
+		return UpdatesModeWebhook, nil
+	}
+	return "", fmt.Errorf("unknown TELEGRAM_UPDATES_MODE %q", mode)
+}
+
+// UsesWebhook reports whether updates have to be routed to WebhookHandler.
+func (n *Notifier) UsesWebhook() bool {
+	return n.updatesMode == UpdatesModeWebhook
+}
+
+// WebhookHandler receives updates from Telegram in webhook mode. Requests
+// without the secret token set on the webhook are rejected.
+func (n *Notifier) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(n.webhookSecret)) != 1 {
+		http.Error(w, "Invalid secret token", http.StatusUnauthorized)
+		return
+	}
+	n.bot.WebhookHandler()(w, r)
+}
+
+// receiveUpdates blocks while processing updates in the configured mode.
+func (n *Notifier) receiveUpdates() {
+	if n.UsesWebhook() {
+		_, err := n.bot.SetWebhook(ctx, &bot.SetWebhookParams{
+			URL:         os.Getenv("TELEGRAM_WEBHOOK_URL"),
+			SecretToken: n.webhookSecret,
+		})
+		if err != nil {
+			log.Printf("Failed to set Telegram webhook: %v", err)
+		}
+		n.bot.StartWebhook(ctx)
+		return
+	}
+
+	// getUpdates is refused while a webhook is set.
+	if _, err := n.bot.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		log.Printf("Failed to delete Telegram webhook: %v", err)
+	}
+	n.bot.Start(ctx)
+}
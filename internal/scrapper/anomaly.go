@@ -0,0 +1,163 @@
+package scrapper
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+	. "validators-health/internal/models"
+	"validators-health/internal/notifier"
+)
+
+const (
+	defaultAnomalySensitivity   = 5
+	defaultAnomalyMinDrop       = 3
+	defaultAnomalyBaselineHours = 24
+	anomalyMinSamples           = 30
+	// madScale turns the median absolute deviation into a standard deviation
+	// estimate for normally distributed data.
+	madScale = 1.4826
+)
+
+// AnomalyConfig controls the efficiency anomaly detector.
+type AnomalyConfig struct {
+	// Sensitivity is the robust z-score a sample has to exceed below the baseline.
+	Sensitivity float64
+	// MinDrop is the minimal drop below the baseline, in percentage points.
+	MinDrop float64
+	// BaselineHours is the length of the baseline window.
+	BaselineHours int
+}
+
+func anomalyConfig() AnomalyConfig {
+	config := AnomalyConfig{
+		Sensitivity:   defaultAnomalySensitivity,
+		MinDrop:       defaultAnomalyMinDrop,
+		BaselineHours: defaultAnomalyBaselineHours,
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ANOMALY_SENSITIVITY"), 64); err == nil && v > 0 {
+		config.Sensitivity = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ANOMALY_MIN_DROP"), 64); err == nil && v >= 0 {
+		config.MinDrop = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("ANOMALY_BASELINE_HOURS")); err == nil && v > 0 {
+		config.BaselineHours = v
+	}
+	return config
+}
+
+type AnomalyStatusInfo struct {
+	Degraded  bool      `json:"degraded"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// detectAnomaly compares a sample with the validator's baseline and returns
+// the evidence if it deviates downwards significantly.
+func detectAnomaly(efficiency float64, baseline EfficiencyBaseline, config AnomalyConfig) (*EfficiencyAnomaly, bool) {
+	if baseline.Samples < anomalyMinSamples {
+		return nil, false
+	}
+
+	drop := baseline.Median - efficiency
+	if drop < config.MinDrop {
+		return nil, false
+	}
+
+	// A perfectly stable validator has zero MAD; use a small floor so a
+	// single real drop still yields a finite score.
+	spread := math.Max(baseline.MAD*madScale, 0.01)
+	score := drop / spread
+	if score < config.Sensitivity {
+		return nil, false
+	}
+
+	return &EfficiencyAnomaly{
+		Efficiency:    efficiency,
+		Baseline:      baseline.Median,
+		MAD:           baseline.MAD,
+		Score:         score,
+		Samples:       baseline.Samples,
+		BaselineHours: config.BaselineHours,
+	}, true
+}
+
+// checkAnomalies raises a "degraded" alert for validators that are still
+// above the threshold but far below their own recent baseline, and a
+// follow-up when they return to it.
+func (s *Scrapper) checkAnomalies(cycle Cycle, scoreboard []CycleScoreboardRow, threshold float64) {
+	config := anomalyConfig()
+	// The baseline window stops short of now so an ongoing drop doesn't
+	// immediately become part of its own baseline.
+	to := time.Now().Truncate(10 * time.Minute).Add(-30 * time.Minute)
+	from := to.Add(-time.Duration(config.BaselineHours) * time.Hour)
+
+	baselines, err := s.ClickhouseService.GetEfficiencyBaselines(from, to, s.CacheService)
+	if err != nil {
+		log.Printf("Failed to get efficiency baselines for cycle %d: %v", cycle.CycleID, err)
+		return
+	}
+
+	for _, row := range scoreboard {
+		baseline, ok := baselines[row.ADNLAddr]
+		if !ok {
+			continue
+		}
+
+		// Below the threshold the regular NOT OK alert takes over; the
+		// anomaly state is kept so it is only cleared once the validator
+		// is back above the threshold and near its baseline.
+		if row.Efficiency < threshold {
+			continue
+		}
+		anomaly, degraded := detectAnomaly(row.Efficiency, baseline, config)
+		if err := s.updateAnomalyStatus(row, degraded, anomaly); err != nil {
+			log.Printf("Failed to check anomaly for validator %s: %v", row.ValidatorADNL, err)
+		}
+	}
+}
+
+func (s *Scrapper) updateAnomalyStatus(row CycleScoreboardRow, degraded bool, anomaly *EfficiencyAnomaly) error {
+	key := fmt.Sprintf("validator_anomaly:%s", row.ValidatorADNL)
+	var previous AnomalyStatusInfo
+	if _, err := s.CacheService.GetCachedData(key, &previous); err != nil {
+		return fmt.Errorf("failed to get cached data: %w", err)
+	}
+	if previous.Degraded == degraded {
+		return nil
+	}
+
+	if err := s.CacheService.CacheData(key, AnomalyStatusInfo{Degraded: degraded, Timestamp: time.Now()}, 0); err != nil {
+		return fmt.Errorf("failed to cache anomaly status: %w", err)
+	}
+
+	alertID, err := s.generateAlertID()
+	if err != nil {
+		return err
+	}
+
+	alert := notifier.Alert{
+		ID:            alertID,
+		Kind:          notifier.AlertKindDegradationCleared,
+		ADNLAddr:      row.ADNLAddr,
+		ValidatorADNL: row.ValidatorADNL,
+		LastAlert:     time.Now(),
+		Efficiency:    row.Efficiency,
+		Timestamp:     uint32(time.Now().Unix()),
+		Anomaly:       anomaly,
+	}
+	if degraded {
+		alert.Kind = notifier.AlertKindDegraded
+	} else {
+		alert.Duration = time.Since(previous.Timestamp)
+	}
+
+	if err := s.Notifier.PublishAlert(alert); err != nil {
+		log.Printf("Failed to publish to Redis: %v", err)
+	}
+	log.Printf("Efficiency anomaly for ADNL %s: degraded=%t", row.ValidatorADNL, degraded)
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package scrapper
+
+import (
+	"math"
+	"testing"
+	. "validators-health/internal/models"
+)
+
+func TestDetectAnomaly(t *testing.T) {
+	config := AnomalyConfig{Sensitivity: 5, MinDrop: 3, BaselineHours: 24}
+
+	tests := []struct {
+		name       string
+		efficiency float64
+		baseline   EfficiencyBaseline
+		want       bool
+		wantScore  float64
+	}{
+		{
+			name:       "too few samples",
+			efficiency: 50,
+			baseline:   EfficiencyBaseline{Median: 95, MAD: 0.5, Samples: anomalyMinSamples - 1},
+		},
+		{
+			name:       "above baseline",
+			efficiency: 99,
+			baseline:   EfficiencyBaseline{Median: 95, MAD: 0.5, Samples: 100},
+		},
+		{
+			name:       "drop below minimum",
+			efficiency: 93,
+			baseline:   EfficiencyBaseline{Median: 95, MAD: 0.1, Samples: 100},
+		},
+		{
+			name:       "drop within noise",
+			efficiency: 88,
+			baseline:   EfficiencyBaseline{Median: 95, MAD: 2, Samples: 100},
+		},
+		{
+			name:       "significant drop",
+			efficiency: 90,
+			baseline:   EfficiencyBaseline{Median: 95, MAD: 0.5, Samples: 100},
+			want:       true,
+			wantScore:  5 / (0.5 * madScale),
+		},
+		{
+			name:       "zero deviation uses the floor",
+			efficiency: 95,
+			baseline:   EfficiencyBaseline{Median: 99, MAD: 0, Samples: 100},
+			want:       true,
+			wantScore:  4 / 0.01,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomaly, ok := detectAnomaly(tt.efficiency, tt.baseline, config)
+			if ok != tt.want {
+				t.Fatalf("detectAnomaly() = %v, want %v", ok, tt.want)
+			}
+			if !ok {
+				if anomaly != nil {
+					t.Errorf("detectAnomaly() returned evidence %+v without an anomaly", anomaly)
+				}
+				return
+			}
+			if math.Abs(anomaly.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("Score = %v, want %v", anomaly.Score, tt.wantScore)
+			}
+			if anomaly.Baseline != tt.baseline.Median || anomaly.Samples != tt.baseline.Samples || anomaly.BaselineHours != config.BaselineHours {
+				t.Errorf("evidence %+v doesn't match baseline %+v", anomaly, tt.baseline)
+			}
+		})
+	}
+}
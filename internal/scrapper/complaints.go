@@ -0,0 +1,50 @@
+package scrapper
+
+import (
+	"context"
+	"log"
+	"time"
+	. "validators-health/internal/models"
+)
+
+const (
+	// complaintMaxAge keeps old complaints from being announced when the
+	// seen markers are empty, e.g. on the first start.
+	complaintMaxAge     = 24 * time.Hour
+	complaintSeenTTL    = 30 * 24 * time.Hour
+	complaintSeenPrefix = "complaint_seen:"
+)
+
+// publishNewComplaints sends complaints that haven't been seen before to
+// the webhooks.
+func (s *Scrapper) publishNewComplaints(cycles []Cycle) {
+	ctx := context.Background()
+	for _, cycle := range cycles {
+		for _, validator := range cycle.CycleInfo.Validators {
+			for _, complaint := range validator.Complaints {
+				createdAt := time.Unix(int64(complaint.CreatedTime), 0)
+				if time.Since(createdAt) > complaintMaxAge {
+					continue
+				}
+
+				isNew, err := s.CacheService.RedisClient.SetNX(ctx, complaintSeenPrefix+complaint.Hash, 1, complaintSeenTTL).Result()
+				if err != nil {
+					log.Printf("Failed to mark complaint %s as seen: %v", complaint.Hash, err)
+					continue
+				}
+				if !isNew {
+					continue
+				}
+
+				complaint := complaint
+				s.Webhooks.Publish(WebhookEvent{
+					Type:      WebhookEventComplaint,
+					Timestamp: createdAt,
+					CycleID:   uint32(cycle.CycleID),
+					ADNLAddr:  validator.ADNLAddr,
+					Complaint: &complaint,
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package scrapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+	. "validators-health/internal/models"
+)
+
+const (
+	defaultMissingGracePeriod = 10 * time.Minute
+	lastSeenTTL               = 7 * 24 * time.Hour
+)
+
+// seenValidator remembers when a validator was last present in the scoreboard.
+type seenValidator struct {
+	ValidatorADNL string    `json:"validator_adnl"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// missingGracePeriod is how long a validator may be absent from the
+// scoreboard before it is reported as missing.
+func missingGracePeriod() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("MISSING_GRACE_PERIOD_SECONDS"))
+	if err != nil || seconds <= 0 {
+		return defaultMissingGracePeriod
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// lastSeenKey holds, per cycle, when each validator was last present in the
+// scoreboard, so the grace period survives restarts and is shared between
+// replicas.
+func lastSeenKey(cycleID int) string {
+	return fmt.Sprintf("validator_last_seen:%d", cycleID)
+}
+
+// checkMissing compares the validator set of the cycle with the scoreboard
+// and switches validators absent for longer than the grace period to the
+// missing status.
+func (s *Scrapper) checkMissing(cycle Cycle, scoreboard []CycleScoreboardRow, notify bool) {
+	ctx := context.Background()
+	now := time.Now()
+	grace := missingGracePeriod()
+	key := lastSeenKey(cycle.CycleID)
+
+	stored, err := s.CacheService.RedisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		log.Printf("Failed to get last seen validators of cycle %d: %v", cycle.CycleID, err)
+		return
+	}
+	lastSeen := make(map[string]seenValidator, len(stored))
+	for adnl, data := range stored {
+		var seen seenValidator
+		if err := json.Unmarshal([]byte(data), &seen); err != nil {
+			log.Printf("Failed to decode last seen entry of %s: %v", adnl, err)
+			continue
+		}
+		lastSeen[adnl] = seen
+	}
+
+	updates := make(map[string]interface{})
+	present := make(map[string]bool, len(scoreboard))
+	for _, row := range scoreboard {
+		present[row.ADNLAddr] = true
+		updates[row.ADNLAddr] = seenValidator{ValidatorADNL: row.ValidatorADNL, Timestamp: now}
+	}
+
+	// Validators absent since the start of the cycle have no scoreboard row
+	// to take the validator ADNL from, so it is looked up in the history.
+	var unresolved []string
+	for _, validator := range cycle.CycleInfo.Validators {
+		if present[validator.ADNLAddr] {
+			continue
+		}
+		seen, ok := lastSeen[validator.ADNLAddr]
+		if !ok {
+			// The grace period begins now.
+			seen = seenValidator{Timestamp: now}
+			lastSeen[validator.ADNLAddr] = seen
+			updates[validator.ADNLAddr] = seen
+		}
+		if seen.ValidatorADNL == "" {
+			unresolved = append(unresolved, validator.ADNLAddr)
+		}
+	}
+	if len(unresolved) > 0 {
+		resolved, err := s.ClickhouseService.GetValidatorADNLs(unresolved)
+		if err != nil {
+			log.Printf("Failed to resolve validator ADNLs of cycle %d: %v", cycle.CycleID, err)
+		}
+		for adnl, validatorADNL := range resolved {
+			seen := lastSeen[adnl]
+			seen.ValidatorADNL = validatorADNL
+			lastSeen[adnl] = seen
+			updates[adnl] = seen
+		}
+	}
+
+	if err := s.saveLastSeen(ctx, key, updates); err != nil {
+		log.Printf("Failed to save last seen validators of cycle %d: %v", cycle.CycleID, err)
+	}
+
+	var missing []CycleScoreboardRow
+	var missingWeight int64
+	for _, validator := range cycle.CycleInfo.Validators {
+		if present[validator.ADNLAddr] {
+			continue
+		}
+		seen := lastSeen[validator.ADNLAddr]
+		if now.Sub(seen.Timestamp) < grace {
+			continue
+		}
+
+		missingWeight += validator.Weight
+		if seen.ValidatorADNL == "" {
+			// Alerts are routed by the validator ADNL; without one nobody
+			// could be subscribed to this validator.
+			log.Printf("No validator ADNL known for missing validator %s, skipping alert", validator.ADNLAddr)
+			continue
+		}
+		missing = append(missing, CycleScoreboardRow{
+			CycleID:       uint32(cycle.CycleID),
+			ADNLAddr:      validator.ADNLAddr,
+			ValidatorADNL: seen.ValidatorADNL,
+			PubKey:        validator.PubKey,
+			Weight:        validator.Weight,
+			Stake:         validator.Stake,
+			Index:         uint16(validator.Index),
+		})
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+
+	// A scoreboard without a large part of the weight is a data problem on
+	// the API side rather than many validators failing at once.
+	if cycle.CycleInfo.TotalWeight > 0 {
+		missingPercent := float64(missingWeight) / float64(cycle.CycleInfo.TotalWeight) * 100
+		if missingPercent > networkDegradationPercent() {
+			log.Printf("Scoreboard for cycle %d lacks %.1f%% of total weight, skipping missing checks", cycle.CycleID, missingPercent)
+			return
+		}
+	}
+
+	for _, row := range missing {
+		if err := s.updateStatus(row, StatusMissing, cycle.CycleInfo.TotalWeight, notify); err != nil {
+			log.Printf("Failed to update missing status for validator %s: %v", row.ADNLAddr, err)
+		}
+	}
+}
+
+func (s *Scrapper) saveLastSeen(ctx context.Context, key string, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(updates))
+	for adnl, seen := range updates {
+		data, err := json.Marshal(seen)
+		if err != nil {
+			return err
+		}
+		values[adnl] = data
+	}
+
+	pipe := s.CacheService.RedisClient.TxPipeline()
+	pipe.HSet(ctx, key, values)
+	pipe.Expire(ctx, key, lastSeenTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,138 @@
+package scrapper
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+	. "validators-health/internal/models"
+	"validators-health/internal/notifier"
+)
+
+const (
+	defaultNetworkDegradationPercent = 30
+	// networkStatusTTL outlives a cycle so the state of an ended cycle
+	// expires on its own.
+	networkStatusTTL = 7 * 24 * time.Hour
+)
+
+// networkStatusKey is per cycle: around an election two cycles are active
+// at once and each has its own health.
+func networkStatusKey(cycleID int) string {
+	return fmt.Sprintf("network_status:%d", cycleID)
+}
+
+type NetworkStatusInfo struct {
+	Degraded  bool      `json:"degraded"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// networkDegradationPercent is the share of total weight that has to be
+// NOT OK in one scrape for the network to be considered degraded.
+func networkDegradationPercent() float64 {
+	percent, err := strconv.ParseFloat(os.Getenv("NETWORK_DEGRADATION_PERCENT"), 64)
+	if err != nil || percent <= 0 {
+		return defaultNetworkDegradationPercent
+	}
+	return percent
+}
+
+func measureNetworkHealth(cycle Cycle, scoreboard []CycleScoreboardRow, threshold float64) NetworkHealth {
+	health := NetworkHealth{
+		CycleID:    uint32(cycle.CycleID),
+		Validators: len(scoreboard),
+	}
+
+	// An empty scoreboard means nothing is being reported at all.
+	if len(scoreboard) == 0 {
+		health.NotOKWeightPercent = 100
+		return health
+	}
+
+	var totalWeight, notOKWeight int64
+	for _, row := range scoreboard {
+		totalWeight += row.Weight
+		if row.Efficiency < threshold {
+			notOKWeight += row.Weight
+			health.NotOKValidators++
+		}
+	}
+	if cycle.CycleInfo.TotalWeight > 0 {
+		totalWeight = cycle.CycleInfo.TotalWeight
+	}
+	if totalWeight > 0 {
+		health.NotOKWeightPercent = float64(notOKWeight) / float64(totalWeight) * 100
+	}
+
+	return health
+}
+
+// checkNetworkHealth publishes a single alert when the network enters or
+// leaves the degraded state and reports whether it is degraded now, in which
+// case individual status alerts are held back.
+func (s *Scrapper) checkNetworkHealth(cycle Cycle, scoreboard []CycleScoreboardRow, threshold float64) bool {
+	s.networkMu.Lock()
+	defer s.networkMu.Unlock()
+
+	health := measureNetworkHealth(cycle, scoreboard, threshold)
+	degraded := health.NotOKWeightPercent > networkDegradationPercent()
+
+	var previous NetworkStatusInfo
+	if _, err := s.CacheService.GetCachedData(networkStatusKey(cycle.CycleID), &previous); err != nil {
+		log.Printf("Failed to get network status: %v", err)
+		return degraded
+	}
+	if previous.Degraded == degraded {
+		return degraded
+	}
+
+	if err := s.CacheService.CacheData(networkStatusKey(cycle.CycleID), NetworkStatusInfo{Degraded: degraded, Timestamp: time.Now()}, networkStatusTTL); err != nil {
+		log.Printf("Failed to cache network status: %v", err)
+	}
+
+	alertID, err := s.generateAlertID()
+	if err != nil {
+		log.Printf("Failed to generate alert ID: %v", err)
+		return degraded
+	}
+
+	alert := notifier.Alert{
+		ID:        alertID,
+		Kind:      notifier.AlertKindNetworkRecovered,
+		LastAlert: time.Now(),
+		Timestamp: uint32(time.Now().Unix()),
+		Network:   &health,
+	}
+	if degraded {
+		alert.Kind = notifier.AlertKindNetworkDegraded
+	} else {
+		alert.Duration = time.Since(previous.Timestamp)
+	}
+
+	if err := s.Notifier.PublishAlert(alert); err != nil {
+		log.Printf("Failed to publish to Redis: %v", err)
+	}
+
+	incident := "network_recovered"
+	if degraded {
+		incident = "network_degraded"
+	}
+	s.Webhooks.Publish(WebhookEvent{
+		Type:     WebhookEventIncident,
+		CycleID:  health.CycleID,
+		Incident: incident,
+		Network:  &health,
+	})
+	log.Printf("Network status changed in cycle %d: %s", cycle.CycleID, formatNetworkState(degraded, health))
+
+	return degraded
+}
+
+func formatNetworkState(degraded bool, health NetworkHealth) string {
+	state := "recovered"
+	if degraded {
+		state = "degraded"
+	}
+	return fmt.Sprintf("%s, %.1f%% of weight not ok (%d/%d validators)", state, health.NotOKWeightPercent, health.NotOKValidators, health.Validators)
+}
@@ -0,0 +1,48 @@
+package scrapper
+
+import (
+	"context"
+	"log"
+	"strconv"
+)
+
+// scrapeProgressKey is a Redis hash of the end of the last successfully
+// scraped window of each tracked cycle. A restart resumes from it and still
+// closes out the cycles that ended in the meantime.
+const scrapeProgressKey = "scrape_progress"
+
+func (s *Scrapper) loadScrapeProgress() map[int]int64 {
+	items, err := s.CacheService.RedisClient.HGetAll(context.Background(), scrapeProgressKey).Result()
+	if err != nil {
+		log.Printf("Failed to load scrape progress: %v", err)
+		return nil
+	}
+
+	progress := make(map[int]int64, len(items))
+	for field, value := range items {
+		cycleID, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		ts, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		progress[cycleID] = ts
+	}
+	return progress
+}
+
+func (s *Scrapper) saveScrapeProgress(cycleID int, ts int64) {
+	err := s.CacheService.RedisClient.HSet(context.Background(), scrapeProgressKey, strconv.Itoa(cycleID), ts).Err()
+	if err != nil {
+		log.Printf("Failed to save scrape progress of cycle %d: %v", cycleID, err)
+	}
+}
+
+func (s *Scrapper) clearScrapeProgress(cycleID int) {
+	err := s.CacheService.RedisClient.HDel(context.Background(), scrapeProgressKey, strconv.Itoa(cycleID)).Err()
+	if err != nil {
+		log.Printf("Failed to clear scrape progress of cycle %d: %v", cycleID, err)
+	}
+}
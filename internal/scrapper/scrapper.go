@@ -13,17 +13,42 @@ import (
 	. "validators-health/internal/models"
 	"validators-health/internal/notifier"
 	"validators-health/internal/services"
+	"validators-health/internal/webhooks"
 )
 
 type ValidatorStatusInfo struct {
 	Status    string    `json:"status"`
 	Timestamp time.Time `json:"timestamp"`
+	// Announced is the last status subscribers were alerted about and
+	// AnnouncedAt when it began. Changes made while alerts are held back are
+	// only announced if the status still differs once they resume.
+	Announced   string    `json:"announced,omitempty"`
+	AnnouncedAt time.Time `json:"announced_at,omitempty"`
 }
 
 type Scrapper struct {
 	ClickhouseService *services.ClickhouseService
 	CacheService      *services.CacheService
 	Notifier          *notifier.Notifier
+	Webhooks          *webhooks.Dispatcher
+
+	mu          sync.Mutex
+	tracked     map[int]*trackedCycle
+	closeOuts   map[int]*trackedCycle
+	nextCycleID int
+
+	networkMu sync.Mutex
+}
+
+// trackedCycle is an active cycle together with the end of the last scraped window.
+type trackedCycle struct {
+	Cycle        Cycle
+	LastScrapeTs int64
+}
+
+// scrapeInterval is the fixed cadence at which every active cycle is scraped.
+func scrapeInterval() time.Duration {
+	return services.ScrapeInterval()
 }
 
 func init() {
@@ -49,6 +74,7 @@ func NewScrapper() (*Scrapper, error) {
 		ClickhouseService: clickhouseService,
 		CacheService:      cacheService,
 		Notifier:          n,
+		Webhooks:          webhooks.NewDispatcher(clickhouseService, cacheService),
 	}, nil
 }
 
@@ -157,15 +183,22 @@ func (s *Scrapper) generateAlertID() (int64, error) {
 	return alertID, nil
 }
 
-func (s *Scrapper) checkStatusChange(ADNLAddr string, validatorADNL string, efficiency float64, threshold float64) error {
-
+func (s *Scrapper) checkStatusChange(row CycleScoreboardRow, totalWeight int64, threshold float64, notify bool) error {
 	var currentStatus ValidatorStatus
-	if efficiency < threshold {
+	if row.Efficiency < threshold {
 		currentStatus = StatusNotOK
 	} else {
 		currentStatus = StatusOK
 	}
 
+	return s.updateStatus(row, currentStatus, totalWeight, notify)
+}
+
+// updateStatus records the validator's current status and, if notify is
+// set, publishes an alert if it differs from the last announced one.
+func (s *Scrapper) updateStatus(row CycleScoreboardRow, currentStatus ValidatorStatus, totalWeight int64, notify bool) error {
+	ADNLAddr, validatorADNL, efficiency := row.ADNLAddr, row.ValidatorADNL, row.Efficiency
+
 	key := fmt.Sprintf("validator_status:%s", validatorADNL)
 	var previousStatusInfo ValidatorStatusInfo
 	found, err := s.CacheService.GetCachedData(key, &previousStatusInfo)
@@ -174,74 +207,283 @@ func (s *Scrapper) checkStatusChange(ADNLAddr string, validatorADNL string, effi
 	}
 
 	if !found {
-
 		previousStatusInfo = ValidatorStatusInfo{
 			Status:    string(StatusUnknown),
 			Timestamp: time.Now(),
 		}
 	}
+	if previousStatusInfo.Announced == "" {
+		previousStatusInfo.Announced = previousStatusInfo.Status
+		previousStatusInfo.AnnouncedAt = previousStatusInfo.Timestamp
+	}
 
 	previousStatus := ValidatorStatus(previousStatusInfo.Status)
-	previousTimestamp := previousStatusInfo.Timestamp
+	announcedStatus := ValidatorStatus(previousStatusInfo.Announced)
+	announcedAt := previousStatusInfo.AnnouncedAt
+
+	changed := currentStatus != previousStatus
+	// A validator that dropped and came back while alerts were held back
+	// is back at its announced status, so nothing is sent for it.
+	announce := notify && currentStatus != announcedStatus
+	if !changed && !announce {
+		return nil
+	}
 
-	if currentStatus != previousStatus {
-		duration := time.Since(previousTimestamp)
-		newStatusInfo := ValidatorStatusInfo{
-			Status:    string(currentStatus),
-			Timestamp: time.Now(),
+	newStatusInfo := previousStatusInfo
+	if changed {
+		newStatusInfo.Status = string(currentStatus)
+		newStatusInfo.Timestamp = time.Now()
+	}
+	if announce {
+		newStatusInfo.Announced = string(currentStatus)
+		newStatusInfo.AnnouncedAt = newStatusInfo.Timestamp
+	}
+	if err := s.CacheService.CacheData(key, newStatusInfo, 0); err != nil {
+		return fmt.Errorf("failed to cache new status: %w", err)
+	}
+
+	if announce {
+		if err := s.publishStatusAlert(row, currentStatus, announcedStatus, announcedAt, totalWeight); err != nil {
+			return err
 		}
-		err = s.CacheService.CacheData(key, newStatusInfo, 0)
+	}
+	if !changed {
+		return nil
+	}
+	log.Printf("Status change detected for ADNL %s: %s -> %s", validatorADNL, previousStatus, currentStatus)
+
+	err = s.ClickhouseService.InsertStatusChange(ADNLAddr, validatorADNL, currentStatus, time.Now(), s.CacheService)
+	if err != nil {
+		log.Printf("Failed to insert status change into ClickHouse: %v", err)
+	}
+
+	err = s.CacheService.PublishEvents([]StreamEvent{{
+		Type:           StreamEventStatus,
+		Timestamp:      newStatusInfo.Timestamp,
+		CycleID:        row.CycleID,
+		ADNLAddr:       ADNLAddr,
+		ValidatorADNL:  validatorADNL,
+		Efficiency:     efficiency,
+		Status:         currentStatus,
+		PreviousStatus: string(previousStatus),
+	}})
+	if err != nil {
+		log.Printf("Failed to publish status event: %v", err)
+	}
+
+	s.Webhooks.Publish(WebhookEvent{
+		Type:           WebhookEventStatus,
+		Timestamp:      newStatusInfo.Timestamp,
+		CycleID:        row.CycleID,
+		ADNLAddr:       ADNLAddr,
+		ValidatorADNL:  validatorADNL,
+		Efficiency:     efficiency,
+		Status:         currentStatus,
+		PreviousStatus: string(previousStatus),
+	})
+
+	return nil
+}
+
+// publishStatusAlert sends the status alert to the validator's subscribers.
+func (s *Scrapper) publishStatusAlert(row CycleScoreboardRow, currentStatus, previousStatus ValidatorStatus, previousTimestamp time.Time, totalWeight int64) error {
+	alertID, err := s.generateAlertID()
+	if err != nil {
+		return fmt.Errorf("failed to generate alert ID: %w", err)
+	}
+
+	alert := notifier.Alert{
+		ID:                  alertID,
+		ADNLAddr:            row.ADNLAddr,
+		ValidatorADNL:       row.ValidatorADNL,
+		Status:              currentStatus,
+		IsAcknowledged:      false,
+		LastAlert:           time.Now(),
+		Efficiency:          row.Efficiency,
+		PreviousStatus:      string(previousStatus),
+		PreviousStatusSince: previousTimestamp,
+		Duration:            time.Since(previousTimestamp),
+		Timestamp:           uint32(time.Now().Unix()),
+	}
+
+	if currentStatus == StatusNotOK || currentStatus == StatusMissing {
+		rules, err := s.ClickhouseService.GetFineRules(s.CacheService)
 		if err != nil {
-			return fmt.Errorf("failed to cache new status: %w", err)
+			log.Printf("Failed to get fine rules, using defaults: %v", err)
 		}
+		exposure := services.EstimateExposure(row.Weight, totalWeight, row.Stake, row.Efficiency, 0, rules)
+		alert.EstimatedExposure = exposure.EstimatedExposureTON
+	}
 
-		alertID, err := s.generateAlertID()
-		if err != nil {
-			return fmt.Errorf("failed to generate alert ID: %w", err)
+	err = s.Notifier.PublishAlert(alert)
+	if err != nil {
+		log.Printf("Failed to publish to Redis: %v", err)
+	} else {
+		log.Printf("Successfully published to validator_notifications")
+	}
+	return nil
+}
+
+// publishSamples streams the scoreboard to live clients.
+func (s *Scrapper) publishSamples(scoreboard []CycleScoreboardRow, fromTs int) {
+	events := make([]StreamEvent, len(scoreboard))
+	for i, row := range scoreboard {
+		events[i] = StreamEvent{
+			Type:          StreamEventSample,
+			Timestamp:     time.Unix(int64(fromTs), 0),
+			CycleID:       row.CycleID,
+			ADNLAddr:      row.ADNLAddr,
+			ValidatorADNL: row.ValidatorADNL,
+			Efficiency:    row.Efficiency,
 		}
+	}
+	if err := s.CacheService.PublishEvents(events); err != nil {
+		log.Printf("Failed to publish scoreboard samples: %v", err)
+	}
+}
+
+func (s *Scrapper) SaveToClickhouse(scoreboard []CycleScoreboardRow, timeStamp int64) error {
+	err := s.ClickhouseService.InsertScoreboard(scoreboard, timeStamp)
+	if err != nil {
+		return fmt.Errorf("error inserting data into ClickHouse: %w", err)
+	}
 
-		alert := notifier.Alert{
-			ID:                  alertID,
-			ADNLAddr:            ADNLAddr,
-			ValidatorADNL:       validatorADNL,
-			Status:              currentStatus,
-			IsAcknowledged:      false,
-			LastAlert:           time.Now(),
-			Efficiency:          efficiency,
-			PreviousStatus:      string(previousStatus),
-			PreviousStatusSince: previousTimestamp,
-			Duration:            duration,
-			Timestamp:           uint32(time.Now().Unix()),
+	log.Println("Data successfully saved to ClickHouse.")
+	return nil
+}
+
+// splitCycles returns the cycles validating at the given moment and the
+// closest upcoming one (already elected but not started yet), if any.
+func splitCycles(cycles []Cycle, now int64) ([]Cycle, *Cycle) {
+	var active []Cycle
+	var next *Cycle
+	for i, cycle := range cycles {
+		info := cycle.CycleInfo
+		if info.UtimeSince <= now && now < info.UtimeUntil {
+			active = append(active, cycle)
+			continue
 		}
+		if info.UtimeSince > now && (next == nil || info.UtimeSince < next.CycleInfo.UtimeSince) {
+			next = &cycles[i]
+		}
+	}
+	return active, next
+}
 
-		err = s.Notifier.PublishAlert(alert)
-		if err != nil {
-			log.Printf("Failed to publish to Redis: %v", err)
-		} else {
-			log.Printf("Successfully published to validator_notifications")
+// handover updates the set of tracked cycles and returns the ones that are no
+// longer active and still need a final close-out scrape. On the first call
+// the progress saved before a restart is picked up.
+func (s *Scrapper) handover(cycles []Cycle, active []Cycle, next *Cycle, now int64) []*trackedCycle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var progress map[int]int64
+	if s.tracked == nil {
+		s.tracked = make(map[int]*trackedCycle)
+		s.closeOuts = make(map[int]*trackedCycle)
+		progress = s.loadScrapeProgress()
+	}
+
+	isActive := make(map[int]bool, len(active))
+	for _, cycle := range active {
+		isActive[cycle.CycleID] = true
+		if tc, ok := s.tracked[cycle.CycleID]; ok {
+			tc.Cycle = cycle
+			continue
+		}
+		log.Printf("Cycle %d is now active (%d - %d)", cycle.CycleID, cycle.CycleInfo.UtimeSince, cycle.CycleInfo.UtimeUntil)
+		lastScrapeTs := now - int64(scrapeInterval().Seconds())
+		if saved, ok := progress[cycle.CycleID]; ok {
+			lastScrapeTs = saved
 		}
-		log.Printf("Status change detected for ADNL %s: %s -> %s", validatorADNL, previousStatus, currentStatus)
+		if lastScrapeTs < cycle.CycleInfo.UtimeSince {
+			lastScrapeTs = cycle.CycleInfo.UtimeSince
+		}
+		s.tracked[cycle.CycleID] = &trackedCycle{Cycle: cycle, LastScrapeTs: lastScrapeTs}
+	}
 
-		err = s.ClickhouseService.InsertStatusChange(ADNLAddr, validatorADNL, currentStatus, time.Now())
-		if err != nil {
-			log.Printf("Failed to insert status change into ClickHouse: %v", err)
+	for cycleID, lastScrapeTs := range progress {
+		if isActive[cycleID] {
+			continue
+		}
+		found := false
+		for _, cycle := range cycles {
+			if cycle.CycleID == cycleID && lastScrapeTs < cycle.CycleInfo.UtimeUntil {
+				log.Printf("Cycle %d ended while stopped, scheduling close-out scrape", cycleID)
+				s.closeOuts[cycleID] = &trackedCycle{Cycle: cycle, LastScrapeTs: lastScrapeTs}
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.clearScrapeProgress(cycleID)
 		}
 	}
 
-	return nil
+	// Close-outs that failed are attempted again.
+	var ended []*trackedCycle
+	for cycleID, tc := range s.closeOuts {
+		ended = append(ended, tc)
+		delete(s.closeOuts, cycleID)
+	}
+	for cycleID, tc := range s.tracked {
+		if isActive[cycleID] {
+			continue
+		}
+		log.Printf("Cycle %d has ended, scheduling close-out scrape", cycleID)
+		ended = append(ended, tc)
+		delete(s.tracked, cycleID)
+	}
+
+	if next != nil && next.CycleID != s.nextCycleID {
+		log.Printf("Next cycle %d starts at %d", next.CycleID, next.CycleInfo.UtimeSince)
+		s.nextCycleID = next.CycleID
+	}
+
+	return ended
 }
 
-func (s *Scrapper) SaveToClickhouse(scoreboard []CycleScoreboardRow, timeStamp int64) {
-	err := s.ClickhouseService.InsertScoreboard(scoreboard, timeStamp)
+// scrapeCycle stores the cycle's scoreboard for the window and checks the
+// statuses. It fails if the scoreboard couldn't be fetched or stored, in
+// which case the window is scraped again next time.
+func (s *Scrapper) scrapeCycle(cycle Cycle, fromTs int, toTs int, threshold float64, checkStatus bool) error {
+	log.Printf("Processing cycle ID: %d (%d - %d)", cycle.CycleID, fromTs, toTs)
+
+	scoreboard, err := s.GetCycleScoreboard(cycle.CycleID, fromTs, toTs)
 	if err != nil {
-		log.Printf("Error inserting data into ClickHouse: %v", err)
-		return
+		return fmt.Errorf("failed to get scoreboard for cycle %d: %w", cycle.CycleID, err)
+	}
+	if err := s.SaveToClickhouse(scoreboard, int64(fromTs*1000)); err != nil {
+		return err
 	}
 
-	log.Println("Data successfully saved to ClickHouse.")
+	if !checkStatus {
+		return nil
+	}
+	s.publishSamples(scoreboard, fromTs)
+	// While the network is degraded statuses are still recorded, only the
+	// alerts are held back until it recovers.
+	notify := !s.checkNetworkHealth(cycle, scoreboard, threshold)
+	if notify {
+		s.checkAnomalies(cycle, scoreboard, threshold)
+	} else {
+		log.Printf("Network is degraded, holding back alerts for cycle %d", cycle.CycleID)
+	}
+	s.checkMissing(cycle, scoreboard, notify)
+
+	for _, row := range scoreboard {
+		err := s.checkStatusChange(row, cycle.CycleInfo.TotalWeight, threshold, notify)
+		if err != nil {
+			log.Printf("Failed to check status change for validator %s: %v", row.ValidatorADNL, err)
+			continue
+		}
+	}
+	return nil
 }
 
 func (s *Scrapper) ProcessCycles(stop <-chan struct{}, threshold float64, cycleId *int, fromTs int, toTs int, isMigrate bool) error {
+	startedAt := time.Now()
+
 	cycles, err := s.GetCycles(cycleId)
 	if err != nil {
 		log.Printf("Failed to get cycles: %v", err)
@@ -249,6 +491,10 @@ func (s *Scrapper) ProcessCycles(stop <-chan struct{}, threshold float64, cycleI
 		return err
 	}
 
+	if err := s.CacheService.CacheData(services.LastCyclesFetchKey, time.Now(), 0); err != nil {
+		log.Printf("Failed to record cycles fetch time: %v", err)
+	}
+
 	if err := s.ClickhouseService.InsertCycles(cycles); err != nil {
 		log.Printf("Failed to insert cycles: %v", err)
 	}
@@ -261,48 +507,75 @@ func (s *Scrapper) ProcessCycles(stop <-chan struct{}, threshold float64, cycleI
 		log.Printf("Failed to insert validators: %v", err)
 	}
 
+	if err := s.ClickhouseService.InsertComplaints(cycles); err != nil {
+		log.Printf("Failed to insert complaints: %v", err)
+	}
+	if !isMigrate {
+		s.publishNewComplaints(cycles)
+		s.checkStakeChanges(cycles)
+	}
+
 	var wg sync.WaitGroup
-	for _, cycle := range cycles {
+	if isMigrate {
+		for _, cycle := range cycles {
+			wg.Add(1)
+			go func(cycle Cycle) {
+				defer wg.Done()
+				if err := s.scrapeCycle(cycle, fromTs, toTs, threshold, false); err != nil {
+					log.Print(err)
+				}
+			}(cycle)
+		}
+		wg.Wait()
+		time.Sleep(1 * time.Second)
+		return nil
+	}
+
+	now := startedAt.Unix()
+	active, next := splitCycles(cycles, now)
+	if len(active) == 0 {
+		log.Printf("No active cycles among %d returned", len(cycles))
+	}
+
+	for _, tc := range s.handover(cycles, active, next, now) {
 		wg.Add(1)
-		go func(cycle Cycle) {
+		go func(tc *trackedCycle) {
 			defer wg.Done()
-			log.Printf("Processing cycle ID: %d", cycle.CycleID)
-
-			scoreboard, err := s.GetCycleScoreboard(cycle.CycleID, fromTs, toTs)
-			if err != nil {
-				log.Printf("Failed to get scoreboard for cycle %d: %v", cycle.CycleID, err)
+			if err := s.scrapeCycle(tc.Cycle, int(tc.LastScrapeTs), int(tc.Cycle.CycleInfo.UtimeUntil), threshold, false); err != nil {
+				log.Print(err)
+				s.mu.Lock()
+				s.closeOuts[tc.Cycle.CycleID] = tc
+				s.mu.Unlock()
 				return
 			}
-			s.SaveToClickhouse(scoreboard, int64(fromTs*1000))
-
-			if !isMigrate {
-				for _, row := range scoreboard {
-					err := s.checkStatusChange(row.ADNLAddr, row.ValidatorADNL, row.Efficiency, threshold)
-					if err != nil {
-						log.Printf("Failed to check status change for validator %s: %v", row.ValidatorADNL, err)
-						continue
-					}
-				}
-			}
-
-		}(cycle)
-		wg.Wait()
-
-		select {
-		case <-stop:
-			log.Println("Scrapper is stopping...")
-			return nil
-		default:
-			time.Sleep(10 * time.Second)
-		}
+			s.clearScrapeProgress(tc.Cycle.CycleID)
+		}(tc)
+	}
 
-		if !isMigrate {
-			log.Println("Waiting for 1 minute before the next update...")
-			time.Sleep(1 * time.Minute)
-		} else {
-			time.Sleep(1 * time.Second)
-		}
+	// A window is only marked as scraped once it was stored; otherwise the
+	// next scrape covers it again.
+	s.mu.Lock()
+	for _, tc := range s.tracked {
+		wg.Add(1)
+		go func(tc *trackedCycle, fromTs int) {
+			defer wg.Done()
+			if err := s.scrapeCycle(tc.Cycle, fromTs, int(now), threshold, true); err != nil {
+				log.Print(err)
+				return
+			}
+			s.mu.Lock()
+			tc.LastScrapeTs = now
+			s.mu.Unlock()
+			s.saveScrapeProgress(tc.Cycle.CycleID, now)
+		}(tc, int(tc.LastScrapeTs))
+	}
+	s.mu.Unlock()
+	wg.Wait()
 
+	select {
+	case <-stop:
+		log.Println("Scrapper is stopping...")
+	case <-time.After(time.Until(startedAt.Add(scrapeInterval()))):
 	}
 
 	return nil
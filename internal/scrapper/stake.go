@@ -0,0 +1,178 @@
+package scrapper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+	. "validators-health/internal/models"
+	"validators-health/internal/notifier"
+	"validators-health/internal/services"
+)
+
+const (
+	// stakeCheckMaxAge keeps cycles that started long ago from being
+	// checked when the markers are empty, e.g. on the first start.
+	stakeCheckMaxAge    = 24 * time.Hour
+	stakeCheckedTTL     = 30 * 24 * time.Hour
+	stakeCheckedPrefix  = "stake_checked:"
+	stakeLookbackCycles = 100
+)
+
+// checkStakeChanges compares the validators elected in each new cycle with
+// the cycles before it, once per cycle, and alerts about significant stake
+// changes and validators leaving or rejoining the elections.
+func (s *Scrapper) checkStakeChanges(cycles []Cycle) {
+	ctx := context.Background()
+	config := services.GetStakeAlertConfig()
+
+	sorted := append([]Cycle(nil), cycles...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CycleID < sorted[j].CycleID })
+	for _, cycle := range sorted {
+		if len(cycle.CycleInfo.Validators) == 0 || time.Since(time.Unix(cycle.CycleInfo.UtimeSince, 0)) > stakeCheckMaxAge {
+			continue
+		}
+
+		key := fmt.Sprintf("%s%d", stakeCheckedPrefix, cycle.CycleID)
+		isNew, err := s.CacheService.RedisClient.SetNX(ctx, key, 1, stakeCheckedTTL).Result()
+		if err != nil {
+			log.Printf("Failed to mark cycle %d as checked for stake changes: %v", cycle.CycleID, err)
+			continue
+		}
+		if !isNew {
+			continue
+		}
+
+		if err := s.detectStakeChanges(cycle, config); err != nil {
+			log.Printf("Failed to check stake changes in cycle %d: %v", cycle.CycleID, err)
+			// Try again on the next iteration.
+			if err := s.CacheService.RedisClient.Del(ctx, key).Err(); err != nil {
+				log.Printf("Failed to unmark cycle %d: %v", cycle.CycleID, err)
+			}
+		}
+	}
+}
+
+func (s *Scrapper) detectStakeChanges(cycle Cycle, config services.StakeAlertConfig) error {
+	cycleID := uint32(cycle.CycleID)
+	previousIDs, err := s.ClickhouseService.GetPreviousCycleIDs(cycleID, stakeLookbackCycles)
+	if err != nil {
+		return err
+	}
+	if len(previousIDs) == 0 {
+		return nil
+	}
+
+	elected := make(map[string]bool, len(cycle.CycleInfo.Validators))
+	adnls := make([]string, 0, len(cycle.CycleInfo.Validators))
+	for _, validator := range cycle.CycleInfo.Validators {
+		elected[validator.ADNLAddr] = true
+		adnls = append(adnls, validator.ADNLAddr)
+	}
+
+	last, err := s.ClickhouseService.GetLastParticipation(adnls, cycleID)
+	if err != nil {
+		return err
+	}
+	for _, validator := range cycle.CycleInfo.Validators {
+		previous, ok := last[validator.ADNLAddr]
+		if !ok {
+			continue
+		}
+		missed := 0
+		for _, id := range previousIDs {
+			if id > previous.CycleID {
+				missed++
+			}
+		}
+
+		change := StakeChange{
+			CycleID:         cycleID,
+			PreviousCycleID: previous.CycleID,
+			Stake:           validator.Stake,
+			PreviousStake:   previous.Stake,
+			ChangePercent:   services.StakeChangePercent(previous.Stake, validator.Stake),
+			MissedCycles:    missed,
+			WalletAddress:   validator.WalletAddress,
+		}
+		switch {
+		case missed >= config.MissedCycles:
+			change.Event = StakeEventRejoined
+		case config.IsSignificantStakeChange(previous.Stake, validator.Stake):
+			change.Event = StakeEventChanged
+		default:
+			continue
+		}
+		s.publishStakeChange(validator.ADNLAddr, change)
+	}
+
+	if len(previousIDs) < config.MissedCycles {
+		return nil
+	}
+	// Validators last elected exactly MissedCycles cycles ago have just
+	// reached the limit.
+	leftCycleID := previousIDs[config.MissedCycles-1]
+	candidates, err := s.ClickhouseService.GetCycleADNLs(leftCycleID)
+	if err != nil {
+		return err
+	}
+	var absent []string
+	for _, adnl := range candidates {
+		if !elected[adnl] {
+			absent = append(absent, adnl)
+		}
+	}
+	last, err = s.ClickhouseService.GetLastParticipation(absent, cycleID)
+	if err != nil {
+		return err
+	}
+	for _, adnl := range absent {
+		previous, ok := last[adnl]
+		if !ok || previous.CycleID != leftCycleID {
+			continue
+		}
+		s.publishStakeChange(adnl, StakeChange{
+			Event:           StakeEventLeft,
+			CycleID:         cycleID,
+			PreviousCycleID: leftCycleID,
+			PreviousStake:   previous.Stake,
+			MissedCycles:    config.MissedCycles,
+			WalletAddress:   previous.WalletAddress,
+		})
+	}
+	return nil
+}
+
+// publishStakeChange alerts the validator's subscribers. Like the other
+// alerts it carries the scoreboard ADNL the subscriptions are keyed by;
+// validators without scoreboard samples yet keep their election ADNL.
+func (s *Scrapper) publishStakeChange(adnl string, change StakeChange) {
+	alertID, err := s.generateAlertID()
+	if err != nil {
+		log.Printf("Failed to publish %s alert for validator %s: %v", change.Event, adnl, err)
+		return
+	}
+
+	validatorADNL := adnl
+	resolved, err := s.ClickhouseService.GetValidatorADNLs([]string{adnl})
+	if err != nil {
+		log.Printf("Failed to resolve validator ADNL of %s: %v", adnl, err)
+	} else if resolved[adnl] != "" {
+		validatorADNL = resolved[adnl]
+	}
+
+	alert := notifier.Alert{
+		ID:            alertID,
+		Kind:          notifier.AlertKind(change.Event),
+		ADNLAddr:      adnl,
+		ValidatorADNL: validatorADNL,
+		LastAlert:     time.Now(),
+		Timestamp:     uint32(time.Now().Unix()),
+		Stake:         &change,
+	}
+	if err := s.Notifier.PublishAlert(alert); err != nil {
+		log.Printf("Failed to publish to Redis: %v", err)
+	}
+	log.Printf("Stake change for ADNL %s in cycle %d: %s", adnl, change.CycleID, change.Event)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+// GetEfficiencyBaselines returns the median and median absolute deviation of
+// every validator's efficiency over [from, to). The window may span a cycle
+// change, so samples of all cycles are included.
+func (s *ClickhouseService) GetEfficiencyBaselines(from, to time.Time, cacheService *CacheService) (map[string]EfficiencyBaseline, error) {
+	fromRounded, toRounded := roundTimeRange(from, to)
+	cacheKey := fmt.Sprintf("EfficiencyBaselines:%d:%d", fromRounded.Unix(), toRounded.Unix())
+
+	var baselines map[string]EfficiencyBaseline
+	found, err := cacheService.GetCachedData(cacheKey, &baselines)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return baselines, nil
+	}
+
+	query := `
+		SELECT
+			adnl_addr,
+			arrayReduce('median', samples) AS med,
+			arrayReduce('median', arrayMap(x -> abs(x - med), samples)) AS mad,
+			length(samples) AS total
+		FROM (
+			SELECT adnl_addr, groupArray(efficiency) AS samples
+			FROM validator_efficiency
+			WHERE
+				date >= toDate(?) AND date <= toDate(?)
+				AND timestamp >= ? AND timestamp < ?
+			GROUP BY adnl_addr
+		)
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, fromRounded, toRounded, fromRounded, toRounded)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	baselines = make(map[string]EfficiencyBaseline)
+	for rows.Next() {
+		var baseline EfficiencyBaseline
+		if err := rows.Scan(&baseline.ADNLAddr, &baseline.Median, &baseline.MAD, &baseline.Samples); err != nil {
+			return nil, err
+		}
+		baselines[baseline.ADNLAddr] = baseline
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := cacheService.CacheData(cacheKey, baselines, 10*time.Minute); err != nil {
+		log.Printf("Error caching efficiency baselines: %v", err)
+	}
+
+	return baselines, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	. "validators-health/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// apiKeysKey maps the SHA-256 of each API key to its description.
+const apiKeysKey = "api_keys"
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// SaveAPIKey stores the description of the key with the given hash.
+func (c *CacheService) SaveAPIKey(hash string, key APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return c.RedisClient.HSet(context.Background(), apiKeysKey, hash, data).Err()
+}
+
+// GetAPIKey returns the key with the given hash, or nil if it is unknown.
+func (c *CacheService) GetAPIKey(hash string) (*APIKey, error) {
+	data, err := c.RedisClient.HGet(context.Background(), apiKeysKey, hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var key APIKey
+	if err := json.Unmarshal([]byte(data), &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (c *CacheService) ListAPIKeys() ([]APIKey, error) {
+	items, err := c.RedisClient.HGetAll(context.Background(), apiKeysKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]APIKey, 0, len(items))
+	for _, data := range items {
+		var key APIKey
+		if err := json.Unmarshal([]byte(data), &key); err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// DeleteAPIKey revokes the key with the given ID and reports whether it
+// existed.
+func (c *CacheService) DeleteAPIKey(id string) (bool, error) {
+	ctx := context.Background()
+	items, err := c.RedisClient.HGetAll(ctx, apiKeysKey).Result()
+	if err != nil {
+		return false, err
+	}
+	for hash, data := range items {
+		var key APIKey
+		if err := json.Unmarshal([]byte(data), &key); err != nil || key.ID != id {
+			continue
+		}
+		return true, c.RedisClient.HDel(ctx, apiKeysKey, hash).Err()
+	}
+	return false, nil
+}
+
+// takeTokenScript refills the bucket for the time passed since the last
+// request and takes one token if there is one. It returns 1 or 0 and, when
+// refused, the milliseconds until the next token.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, wait}
+`)
+
+// TakeToken takes one request from the token bucket of the given name,
+// which allows perMinute requests a minute with bursts of the same size.
+// When refused it returns how long to wait.
+func (c *CacheService) TakeToken(bucket string, perMinute int) (bool, time.Duration, error) {
+	rate := float64(perMinute) / 60
+	result, err := takeTokenScript.Run(context.Background(), c.RedisClient,
+		[]string{"rate_limit:" + bucket},
+		rate, perMinute, time.Now().UnixMilli(),
+	).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	allowed, _ := result[0].(int64)
+	wait, _ := result[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	. "validators-health/internal/models"
+)
+
+// InsertAuditLog records an administrative action.
+func (s *ClickhouseService) InsertAuditLog(entry AuditLogEntry) error {
+	query := "INSERT INTO audit_log (timestamp, user_id, username, chat_id, action, details) VALUES (?, ?, ?, ?, ?, ?)"
+	ctx := context.Background()
+	err := s.DB.Exec(ctx, query, entry.Timestamp.Unix(), entry.UserID, entry.Username, entry.ChatID, entry.Action, entry.Details)
+	if err != nil {
+		return fmt.Errorf("error inserting audit log entry into ClickHouse: %w", err)
+	}
+	return nil
+}
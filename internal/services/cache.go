@@ -11,6 +11,9 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// LastCyclesFetchKey holds the time of the last successful GetCycles call.
+const LastCyclesFetchKey = "last_cycles_fetch"
+
 type CacheService struct {
 	RedisClient *redis.Client
 }
@@ -351,8 +351,12 @@ func (s *ClickhouseService) fetchEfficiencyChartDataFromDB(adnl string, from, to
 	return efficiencies, nil
 }
 
+func statusHistoryKey(adnl string) string {
+	return fmt.Sprintf("status_history:%s", adnl)
+}
+
 func (s *ClickhouseService) GetStatusHistory(adnl string, cacheService *CacheService) ([]ValidatorStatusHistory, error) {
-	cacheKey := fmt.Sprintf("status_history:%s", adnl)
+	cacheKey := statusHistoryKey(adnl)
 	var history []ValidatorStatusHistory
 
 	found, err := cacheService.GetCachedData(cacheKey, &history)
@@ -396,6 +400,90 @@ func (s *ClickhouseService) GetStatusHistory(adnl string, cacheService *CacheSer
 	return history, nil
 }
 
+func (s *ClickhouseService) GetCycleInfo(cycleID uint32) (*CycleInfo, error) {
+	query := `
+		SELECT utime_since, utime_until, total_weight
+		FROM cycles_info FINAL
+		WHERE cycle_id = ?
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, cycleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var utimeSince, utimeUntil time.Time
+	var info CycleInfo
+	if err := rows.Scan(&utimeSince, &utimeUntil, &info.TotalWeight); err != nil {
+		return nil, err
+	}
+	info.UtimeSince = utimeSince.Unix()
+	info.UtimeUntil = utimeUntil.Unix()
+
+	return &info, nil
+}
+
+// GetValidatorInfo returns the validator's entry from the latest cycle it
+// was elected in, or nil if it was never seen.
+func (s *ClickhouseService) GetValidatorInfo(adnl string, cacheService *CacheService) (*ValidatorInfo, error) {
+	cacheKey := fmt.Sprintf("ValidatorInfo:%s", adnl)
+	var info ValidatorInfo
+	found, err := cacheService.GetCachedData(cacheKey, &info)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &info, nil
+	}
+
+	query := `
+		SELECT cycle_id, adnl_addr, pubkey, weight, "index", stake, max_factor, wallet_address
+		FROM validators FINAL
+		WHERE adnl_addr = ?
+		ORDER BY cycle_id DESC
+		LIMIT 1
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, adnl)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	if err := rows.Scan(&info.CycleID, &info.ADNLAddr, &info.PubKey, &info.Weight, &info.Index, &info.Stake, &info.MaxFactor, &info.WalletAddress); err != nil {
+		return nil, err
+	}
+
+	if err := cacheService.CacheData(cacheKey, info, 10*time.Minute); err != nil {
+		log.Printf("Error caching validator info for %s: %v", adnl, err)
+	}
+
+	return &info, nil
+}
+
+// GetLatestSampleTime returns the timestamp of the newest scoreboard sample.
+func (s *ClickhouseService) GetLatestSampleTime() (time.Time, error) {
+	query := `
+		SELECT max(timestamp)
+		FROM validator_efficiency
+		WHERE date >= today() - 1
+	`
+	ctx := context.Background()
+	var latest time.Time
+	if err := s.DB.QueryRow(ctx, query).Scan(&latest); err != nil {
+		return time.Time{}, err
+	}
+	return latest, nil
+}
+
 func (s *ClickhouseService) InsertScoreboard(scoreboard []CycleScoreboardRow, timeStamp int64) error {
 	ctx := context.Background()
 	batch, err := s.DB.PrepareBatch(ctx, "INSERT INTO validator_efficiency (timestamp, validator_adnl, adnl_addr, cycle_id, efficiency, stake, weight, index, pub_key_hash, utime_since, utime_until)")
@@ -412,13 +500,18 @@ func (s *ClickhouseService) InsertScoreboard(scoreboard []CycleScoreboardRow, ti
 	return batch.Send()
 }
 
-func (s *ClickhouseService) InsertStatusChange(adnlAddr string, validatorAdnl string, status ValidatorStatus, timestamp time.Time) error {
+// InsertStatusChange records a status change and drops the validator's
+// cached history so it includes the change.
+func (s *ClickhouseService) InsertStatusChange(adnlAddr string, validatorAdnl string, status ValidatorStatus, timestamp time.Time, cacheService *CacheService) error {
 	query := "INSERT INTO validator_status_history (adnl_addr, validator_adnl, timestamp, status) VALUES (?, ?, ?, ?)"
 	ctx := context.Background()
 	err := s.DB.Exec(ctx, query, adnlAddr, validatorAdnl, timestamp.Unix(), string(status))
 	if err != nil {
 		return fmt.Errorf("error inserting status change into ClickHouse: %w", err)
 	}
+	if err := cacheService.RedisClient.Del(ctx, statusHistoryKey(adnlAddr)).Err(); err != nil {
+		log.Printf("Error invalidating status history for %s: %v", adnlAddr, err)
+	}
 	return nil
 }
 
@@ -486,6 +579,39 @@ func (s *ClickhouseService) InsertValidators(cycles []Cycle) error {
 	return batch.Send()
 }
 
+func (s *ClickhouseService) InsertComplaints(cycles []Cycle) error {
+	ctx := context.Background()
+	batch, err := s.DB.PrepareBatch(ctx, "INSERT INTO complaints (cycle_id, election_id, hash, adnl_addr, pubkey, description, created_time, severity, suggested_fine, suggested_fine_part, approved_percent, is_passed)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare batch for complaints: %w", err)
+	}
+
+	for _, cycle := range cycles {
+		for _, validator := range cycle.CycleInfo.Validators {
+			for _, complaint := range validator.Complaints {
+				if err := batch.Append(
+					uint32(cycle.CycleID),
+					uint32(complaint.ElectionId),
+					complaint.Hash,
+					validator.ADNLAddr,
+					complaint.Pubkey,
+					complaint.Description,
+					time.Unix(int64(complaint.CreatedTime), 0),
+					int32(complaint.Severity),
+					complaint.SuggestedFine,
+					int64(complaint.SuggestedFinePart),
+					complaint.ApprovedPercent,
+					complaint.IsPassed,
+				); err != nil {
+					return fmt.Errorf("failed to append complaint: %w", err)
+				}
+			}
+		}
+	}
+
+	return batch.Send()
+}
+
 func roundTimeRange(from, to time.Time) (time.Time, time.Time) {
 	fromRounded := from.Round(time.Minute)
 	toRounded := to.Round(time.Minute)
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+// CompareValidators returns the validators' efficiency series next to the
+// network's p10, median and p90, and their percentile rank in each cycle of
+// the range. Series use the same intervals as GetValidatorsStatuses.
+func (s *ClickhouseService) CompareValidators(adnls []string, from, to time.Time, cacheService *CacheService) (*Comparison, error) {
+	fromRounded, toRounded := roundTimeRange(from, to)
+	sorted := append([]string(nil), adnls...)
+	sort.Strings(sorted)
+	cacheKey := fmt.Sprintf("Comparison:%s:%d:%d", strings.Join(sorted, ","), fromRounded.Unix(), toRounded.Unix())
+
+	var comparison Comparison
+	found, err := cacheService.GetCachedData(cacheKey, &comparison)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &comparison, nil
+	}
+
+	comparison = Comparison{From: fromRounded.Unix(), To: toRounded.Unix()}
+	comparison.Network, err = s.fetchNetworkBands(fromRounded, toRounded)
+	if err != nil {
+		return nil, err
+	}
+	series, err := s.getCachedStatuses(adnls, fromRounded, toRounded, 0, cacheService)
+	if err != nil {
+		return nil, err
+	}
+	ranks, err := s.fetchCycleRanks(adnls, fromRounded, toRounded)
+	if err != nil {
+		return nil, err
+	}
+	for _, adnl := range adnls {
+		validator := ValidatorComparison{ADNLAddr: adnl, Series: series[adnl], Ranks: ranks[adnl]}
+		if validator.Series == nil {
+			validator.Series = map[uint32]float64{}
+		}
+		if validator.Ranks == nil {
+			validator.Ranks = []CycleRank{}
+		}
+		comparison.Validators = append(comparison.Validators, validator)
+	}
+
+	if err := cacheService.CacheData(cacheKey, comparison, 5*time.Minute); err != nil {
+		log.Printf("Error caching comparison: %v", err)
+	}
+	return &comparison, nil
+}
+
+func (s *ClickhouseService) fetchNetworkBands(from, to time.Time) ([]NetworkBand, error) {
+	intervalSeconds := uint32(to.Sub(from).Seconds()) / 60
+	if intervalSeconds == 0 {
+		return nil, fmt.Errorf("invalid date interval")
+	}
+
+	// Each validator counts once per interval, however many samples it has.
+	query := `
+		SELECT interval_start, quantiles(0.1, 0.5, 0.9)(avg_efficiency)
+		FROM (
+			SELECT
+				toUnixTimestamp(toStartOfInterval(toDateTime(timestamp), INTERVAL ? SECOND, ?)) AS interval_start,
+				adnl_addr,
+				AVG(efficiency) AS avg_efficiency
+			FROM validator_efficiency
+			WHERE
+				date >= toDate(?) AND date <= toDate(?)
+				AND timestamp >= ? AND timestamp <= ?
+			GROUP BY interval_start, adnl_addr
+		)
+		GROUP BY interval_start
+		ORDER BY interval_start
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, intervalSeconds, from, from, to, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bands := make([]NetworkBand, 0)
+	for rows.Next() {
+		var band NetworkBand
+		var levels []float64
+		if err := rows.Scan(&band.Timestamp, &levels); err != nil {
+			return nil, err
+		}
+		if len(levels) == 3 {
+			band.P10, band.Median, band.P90 = levels[0], levels[1], levels[2]
+		}
+		bands = append(bands, band)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return bands, nil
+}
+
+// fetchCycleRanks ranks validators by their average efficiency over each
+// whole cycle overlapping [from, to], so a short range doesn't rank them on
+// a handful of samples.
+func (s *ClickhouseService) fetchCycleRanks(adnls []string, from, to time.Time) (map[string][]CycleRank, error) {
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, `
+		SELECT cycle_id, utime_since, utime_until
+		FROM cycles_info FINAL
+		WHERE utime_since <= ? AND utime_until >= ?
+		ORDER BY cycle_id
+	`, to, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cycleIDs []uint32
+	var since, until time.Time
+	for rows.Next() {
+		var cycleID uint32
+		var utimeSince, utimeUntil time.Time
+		if err := rows.Scan(&cycleID, &utimeSince, &utimeUntil); err != nil {
+			return nil, err
+		}
+		if len(cycleIDs) == 0 || utimeSince.Before(since) {
+			since = utimeSince
+		}
+		if utimeUntil.After(until) {
+			until = utimeUntil
+		}
+		cycleIDs = append(cycleIDs, cycleID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	ranks := make(map[string][]CycleRank)
+	if len(cycleIDs) == 0 {
+		return ranks, nil
+	}
+
+	cyclePlaceholders := make([]string, len(cycleIDs))
+	params := []interface{}{since, until}
+	for i, cycleID := range cycleIDs {
+		cyclePlaceholders[i] = "?"
+		params = append(params, cycleID)
+	}
+	adnlPlaceholders := make([]string, len(adnls))
+	for i, adnl := range adnls {
+		adnlPlaceholders[i] = "?"
+		params = append(params, adnl)
+	}
+	query := fmt.Sprintf(`
+		SELECT adnl_addr, cycle_id, avg_efficiency, if(validators > 1, position * 100, 100), validators
+		FROM (
+			SELECT
+				adnl_addr,
+				cycle_id,
+				avg_efficiency,
+				percent_rank() OVER (PARTITION BY cycle_id ORDER BY avg_efficiency) AS position,
+				count() OVER (PARTITION BY cycle_id) AS validators
+			FROM (
+				SELECT adnl_addr, cycle_id, AVG(efficiency) AS avg_efficiency
+				FROM validator_efficiency
+				WHERE
+					date >= toDate(?) AND date <= toDate(?)
+					AND cycle_id IN (%s)
+				GROUP BY adnl_addr, cycle_id
+			)
+		)
+		WHERE adnl_addr IN (%s)
+		ORDER BY adnl_addr, cycle_id
+	`, strings.Join(cyclePlaceholders, ","), strings.Join(adnlPlaceholders, ","))
+	rankRows, err := s.DB.Query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rankRows.Close()
+
+	for rankRows.Next() {
+		var adnl string
+		var rank CycleRank
+		if err := rankRows.Scan(&adnl, &rank.CycleID, &rank.AvgEfficiency, &rank.PercentileRank, &rank.Validators); err != nil {
+			return nil, err
+		}
+		ranks[adnl] = append(ranks[adnl], rank)
+	}
+	if err := rankRows.Err(); err != nil {
+		return nil, err
+	}
+	return ranks, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+// GetDigest summarizes each validator's efficiency, incidents, acknowledgements
+// and complaints over the range. Validators are identified by their
+// validator ADNL, the same identity subscriptions use.
+func (s *ClickhouseService) GetDigest(adnls []string, from, to time.Time, threshold float64) ([]ValidatorDigest, error) {
+	if len(adnls) == 0 {
+		return nil, nil
+	}
+
+	digests := make(map[string]*ValidatorDigest, len(adnls))
+	placeholders := make([]string, len(adnls))
+	adnlParams := make([]interface{}, len(adnls))
+	for i, adnl := range adnls {
+		digests[adnl] = &ValidatorDigest{ValidatorADNL: adnl}
+		placeholders[i] = "?"
+		adnlParams[i] = adnl
+	}
+	inList := strings.Join(placeholders, ",")
+	ctx := context.Background()
+
+	efficiencyQuery := fmt.Sprintf(`
+		SELECT
+			validator_adnl,
+			AVG(efficiency) AS avg_efficiency,
+			sumIf(gap, efficiency < ?) AS seconds_below
+		FROM (
+			SELECT
+				validator_adnl,
+				efficiency,
+				toInt64(leadInFrame(toUnixTimestamp(timestamp), 1, toUnixTimestamp(timestamp))
+					OVER (PARTITION BY validator_adnl ORDER BY timestamp ROWS BETWEEN CURRENT ROW AND 1 FOLLOWING))
+					- toInt64(toUnixTimestamp(timestamp)) AS gap
+			FROM validator_efficiency
+			WHERE
+				date >= toDate(?) AND date <= toDate(?)
+				AND timestamp >= ? AND timestamp < ?
+				AND validator_adnl IN (%s)
+		)
+		GROUP BY validator_adnl
+	`, inList)
+	params := append([]interface{}{threshold, from, to, from, to}, adnlParams...)
+	rows, err := s.DB.Query(ctx, efficiencyQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var adnl string
+		var avgEfficiency float64
+		var secondsBelow int64
+		if err := rows.Scan(&adnl, &avgEfficiency, &secondsBelow); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if digest, ok := digests[adnl]; ok {
+			digest.AvgEfficiency = avgEfficiency
+			digest.SecondsBelowThreshold = secondsBelow
+			digest.HasData = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	historyQuery := fmt.Sprintf(`
+		SELECT
+			validator_adnl,
+			countIf(status IN ('not ok', 'missing')) AS incidents,
+			countIf(status = 'acknowledged') AS acks
+		FROM validator_status_history
+		WHERE timestamp >= ? AND timestamp < ? AND validator_adnl IN (%s)
+		GROUP BY validator_adnl
+	`, inList)
+	params = append([]interface{}{from, to}, adnlParams...)
+	rows, err = s.DB.Query(ctx, historyQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var adnl string
+		var incidents, acks uint64
+		if err := rows.Scan(&adnl, &incidents, &acks); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if digest, ok := digests[adnl]; ok {
+			digest.Incidents = incidents
+			digest.Acks = acks
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Complaints only carry the ADNL address; map it to the validator ADNL
+	// through the samples of the range.
+	complaintsQuery := fmt.Sprintf(`
+		SELECT e.validator_adnl, uniqExact(c.hash) AS complaints
+		FROM complaints AS c FINAL
+		INNER JOIN (
+			SELECT DISTINCT adnl_addr, validator_adnl
+			FROM validator_efficiency
+			WHERE
+				date >= toDate(?) AND date <= toDate(?)
+				AND timestamp >= ? AND timestamp < ?
+				AND validator_adnl IN (%s)
+		) AS e ON c.adnl_addr = e.adnl_addr
+		WHERE c.created_time >= ? AND c.created_time < ?
+		GROUP BY e.validator_adnl
+	`, inList)
+	params = append([]interface{}{from, to, from, to}, adnlParams...)
+	params = append(params, from, to)
+	rows, err = s.DB.Query(ctx, complaintsQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var adnl string
+		var complaints uint64
+		if err := rows.Scan(&adnl, &complaints); err != nil {
+			return nil, err
+		}
+		if digest, ok := digests[adnl]; ok {
+			digest.Complaints = complaints
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]ValidatorDigest, 0, len(adnls))
+	for _, adnl := range adnls {
+		result = append(result, *digests[adnl])
+	}
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+const (
+	nanoTON = 1_000_000_000
+	// finePartDenominator is the fixed-point base of Complaint.SuggestedFinePart.
+	finePartDenominator = 1 << 32
+	// defaultBaseFine is the flat fine of a standard low-efficiency complaint.
+	defaultBaseFine                   = 101 * nanoTON
+	defaultPenaltyEfficiencyThreshold = 80
+)
+
+// FineRules describe the fine a validator can expect from a complaint: a
+// flat part in nanoTON plus a share of the stake in 1/2^32 units.
+type FineRules struct {
+	BaseFine int64 `json:"base_fine"`
+	FinePart int64 `json:"fine_part"`
+}
+
+// Fine returns the fine in nanoTON for the given stake.
+func (r FineRules) Fine(stake int64) int64 {
+	return r.BaseFine + int64(float64(stake)*float64(r.FinePart)/finePartDenominator)
+}
+
+// PenaltyEfficiencyThreshold is the efficiency below which a validator is
+// expected to receive a complaint.
+func PenaltyEfficiencyThreshold() float64 {
+	threshold, err := strconv.ParseFloat(os.Getenv("PENALTY_EFFICIENCY_THRESHOLD"), 64)
+	if err != nil || threshold <= 0 {
+		return defaultPenaltyEfficiencyThreshold
+	}
+	return threshold
+}
+
+// EstimateExposure translates efficiency into reward share and fines. The
+// expected share is the validator's weight in the cycle, and the effective
+// share is scaled down by efficiency. Complaints that were already filed
+// define the exposure; without them the fine rules apply once efficiency is
+// below the penalty threshold.
+func EstimateExposure(weight, totalWeight, stake int64, efficiency float64, complaintFines int64, rules FineRules) RewardExposure {
+	var exposure RewardExposure
+	if totalWeight > 0 {
+		exposure.ExpectedRewardShare = float64(weight) / float64(totalWeight)
+	}
+
+	factor := efficiency / 100
+	if factor > 1 {
+		factor = 1
+	}
+	if factor < 0 {
+		factor = 0
+	}
+	exposure.EffectiveRewardShare = exposure.ExpectedRewardShare * factor
+	exposure.RewardShareLoss = exposure.ExpectedRewardShare - exposure.EffectiveRewardShare
+
+	switch {
+	case complaintFines > 0:
+		exposure.EstimatedExposureTON = float64(complaintFines) / nanoTON
+	case efficiency < PenaltyEfficiencyThreshold():
+		exposure.EstimatedExposureTON = float64(rules.Fine(stake)) / nanoTON
+	}
+
+	return exposure
+}
+
+// GetFineRules derives the current fine rules from the complaints seen in
+// the network over the last 30 days, falling back to the standard fine.
+func (s *ClickhouseService) GetFineRules(cacheService *CacheService) (FineRules, error) {
+	cacheKey := "FineRules"
+	rules := FineRules{BaseFine: defaultBaseFine}
+
+	found, err := cacheService.GetCachedData(cacheKey, &rules)
+	if err != nil {
+		return rules, err
+	}
+	if found {
+		return rules, nil
+	}
+
+	query := `
+		SELECT
+			count() AS total,
+			toInt64(median(suggested_fine)) AS base_fine,
+			toInt64(median(suggested_fine_part)) AS fine_part
+		FROM complaints FINAL
+		WHERE created_time >= now() - INTERVAL 30 DAY
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query)
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var total uint64
+		var baseFine, finePart int64
+		if err := rows.Scan(&total, &baseFine, &finePart); err != nil {
+			return rules, err
+		}
+		if total > 0 {
+			rules = FineRules{BaseFine: baseFine, FinePart: finePart}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return rules, err
+	}
+
+	if err := cacheService.CacheData(cacheKey, rules, time.Hour); err != nil {
+		log.Printf("Error caching fine rules: %v", err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	. "validators-health/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	groupNamesKey         = "validator_groups"
+	groupMembersKeyPrefix = "validator_group:"
+)
+
+func groupMembersKey(name string) string {
+	return groupMembersKeyPrefix + name
+}
+
+// SaveGroup creates or replaces a user-defined group.
+func (c *CacheService) SaveGroup(name string, members []string) error {
+	ctx := context.Background()
+	key := groupMembersKey(name)
+
+	pipe := c.RedisClient.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(members) > 0 {
+		values := make([]interface{}, len(members))
+		for i, member := range members {
+			values[i] = member
+		}
+		pipe.SAdd(ctx, key, values...)
+	}
+	pipe.SAdd(ctx, groupNamesKey, name)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *CacheService) DeleteGroup(name string) error {
+	ctx := context.Background()
+	pipe := c.RedisClient.TxPipeline()
+	pipe.Del(ctx, groupMembersKey(name))
+	pipe.SRem(ctx, groupNamesKey, name)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetGroup returns a user-defined group, or nil if there is none with this name.
+func (c *CacheService) GetGroup(name string) (*ValidatorGroup, error) {
+	ctx := context.Background()
+	exists, err := c.RedisClient.SIsMember(ctx, groupNamesKey, name).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	members, err := c.RedisClient.SMembers(ctx, groupMembersKey(name)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	sort.Strings(members)
+
+	return &ValidatorGroup{Name: name, Kind: GroupKindCustom, Members: members}, nil
+}
+
+func (c *CacheService) ListGroups() ([]ValidatorGroup, error) {
+	names, err := c.RedisClient.SMembers(context.Background(), groupNamesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var groups []ValidatorGroup
+	for _, name := range names {
+		group, err := c.GetGroup(name)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil {
+			groups = append(groups, *group)
+		}
+	}
+	return groups, nil
+}
+
+// GetGroupsOf returns the names of the user-defined groups the validator belongs to.
+func (c *CacheService) GetGroupsOf(adnl string) ([]string, error) {
+	ctx := context.Background()
+	names, err := c.RedisClient.SMembers(ctx, groupNamesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	for _, name := range names {
+		isMember, err := c.RedisClient.SIsMember(ctx, groupMembersKey(name), adnl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if isMember {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+// GetWalletGroups groups the validators of the latest known cycle by owner wallet.
+func (s *ClickhouseService) GetWalletGroups(cacheService *CacheService) ([]ValidatorGroup, error) {
+	cacheKey := "WalletGroups"
+	var groups []ValidatorGroup
+
+	found, err := cacheService.GetCachedData(cacheKey, &groups)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return groups, nil
+	}
+
+	query := `
+		SELECT wallet_address, arraySort(groupUniqArray(adnl_addr)) AS members
+		FROM validators FINAL
+		WHERE cycle_id = (SELECT max(cycle_id) FROM validators) AND wallet_address != ''
+		GROUP BY wallet_address
+		ORDER BY wallet_address
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group := ValidatorGroup{Kind: GroupKindWallet}
+		if err := rows.Scan(&group.Name, &group.Members); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := cacheService.CacheData(cacheKey, groups, 10*time.Minute); err != nil {
+		log.Printf("Error caching wallet groups: %v", err)
+	}
+
+	return groups, nil
+}
+
+// GetWalletGroup returns the wallet group of the given owner wallet, or nil
+// if no validator of the latest cycle uses it.
+func (s *ClickhouseService) GetWalletGroup(wallet string, cacheService *CacheService) (*ValidatorGroup, error) {
+	groups, err := s.GetWalletGroups(cacheService)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.Name == wallet {
+			return &group, nil
+		}
+	}
+	return nil, nil
+}
+
+// ResolveGroup finds a user-defined group by name first and falls back to
+// the wallet group with that address.
+func (s *ClickhouseService) ResolveGroup(name string, cacheService *CacheService) (*ValidatorGroup, error) {
+	group, err := cacheService.GetGroup(name)
+	if err != nil || group != nil {
+		return group, err
+	}
+	return s.GetWalletGroup(name, cacheService)
+}
+
+// GetGroupsOf returns every group, wallet and user-defined, the validator belongs to.
+func (s *ClickhouseService) GetGroupsOf(adnl string, cacheService *CacheService) ([]ValidatorGroup, error) {
+	var result []ValidatorGroup
+
+	walletGroups, err := s.GetWalletGroups(cacheService)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range walletGroups {
+		for _, member := range group.Members {
+			if member == adnl {
+				result = append(result, group)
+				break
+			}
+		}
+	}
+
+	names, err := cacheService.GetGroupsOf(adnl)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		group, err := cacheService.GetGroup(name)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil {
+			result = append(result, *group)
+		}
+	}
+
+	return result, nil
+}
+
+// GetValidatorsSummary returns per-validator average efficiency and stake over
+// the range. An empty list means all validators; only that case is cached.
+func (s *ClickhouseService) GetValidatorsSummary(adnls []string, from, to time.Time, cacheService *CacheService) (map[string]ValidatorSummary, error) {
+	fromRounded, toRounded := roundTimeRange(from, to)
+	if len(adnls) > 0 {
+		return s.fetchValidatorsSummary(adnls, fromRounded, toRounded)
+	}
+
+	cacheKey := fmt.Sprintf("ValidatorsSummary:%d:%d", fromRounded.Unix(), toRounded.Unix())
+	var summaries map[string]ValidatorSummary
+	found, err := cacheService.GetCachedData(cacheKey, &summaries)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return summaries, nil
+	}
+
+	summaries, err = s.fetchValidatorsSummary(nil, fromRounded, toRounded)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cacheService.CacheData(cacheKey, summaries, 5*time.Minute); err != nil {
+		log.Printf("Error caching validators summary: %v", err)
+	}
+
+	return summaries, nil
+}
+
+func (s *ClickhouseService) fetchValidatorsSummary(adnls []string, from, to time.Time) (map[string]ValidatorSummary, error) {
+	adnlQuery := ""
+	params := []interface{}{from, to, from, to}
+	if len(adnls) > 0 {
+		placeholders := make([]string, len(adnls))
+		for i, adnl := range adnls {
+			placeholders[i] = "?"
+			params = append(params, adnl)
+		}
+		adnlQuery = fmt.Sprintf("AND adnl_addr IN (%s)", strings.Join(placeholders, ","))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			adnl_addr,
+			AVG(efficiency) AS avg_efficiency,
+			toInt64(AVG(stake)) AS avg_stake
+		FROM validator_efficiency
+		WHERE
+			date >= toDate(?) AND date <= toDate(?)
+			AND timestamp >= ? AND timestamp <= ?
+			%s
+		GROUP BY adnl_addr
+	`, adnlQuery)
+
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make(map[string]ValidatorSummary)
+	for rows.Next() {
+		var summary ValidatorSummary
+		if err := rows.Scan(&summary.ADNLAddr, &summary.AvgEfficiency, &summary.Stake); err != nil {
+			return nil, err
+		}
+		summaries[summary.ADNLAddr] = summary
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+// AggregateGroup computes group-level efficiency figures. Members without
+// samples in the range are counted in Validators but not in ActiveValidators.
+func AggregateGroup(group ValidatorGroup, summaries map[string]ValidatorSummary, threshold float64) GroupStats {
+	stats := GroupStats{
+		Name:       group.Name,
+		Kind:       group.Kind,
+		Validators: len(group.Members),
+	}
+
+	var efficiencySum, weightedSum float64
+	for _, member := range group.Members {
+		summary, ok := summaries[member]
+		if !ok {
+			continue
+		}
+		if stats.ActiveValidators == 0 || summary.AvgEfficiency < stats.MinEfficiency {
+			stats.MinEfficiency = summary.AvgEfficiency
+		}
+		stats.ActiveValidators++
+		efficiencySum += summary.AvgEfficiency
+		weightedSum += summary.AvgEfficiency * float64(summary.Stake)
+		stats.TotalStake += summary.Stake
+		if summary.AvgEfficiency < threshold {
+			stats.BelowThreshold++
+		}
+	}
+
+	if stats.ActiveValidators > 0 {
+		stats.AvgEfficiency = efficiencySum / float64(stats.ActiveValidators)
+	}
+	if stats.TotalStake > 0 {
+		stats.StakeWeightedEfficiency = weightedSum / float64(stats.TotalStake)
+	}
+
+	return stats
+}
+
+// GetGroupsStats returns aggregates for all wallet and user-defined groups.
+func (s *ClickhouseService) GetGroupsStats(from, to time.Time, threshold float64, cacheService *CacheService) ([]GroupStats, error) {
+	walletGroups, err := s.GetWalletGroups(cacheService)
+	if err != nil {
+		return nil, err
+	}
+	customGroups, err := cacheService.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := s.GetValidatorsSummary(nil, from, to, cacheService)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]GroupStats, 0, len(walletGroups)+len(customGroups))
+	for _, group := range append(customGroups, walletGroups...) {
+		stats = append(stats, AggregateGroup(group, summaries, threshold))
+	}
+	return stats, nil
+}
+
+// CountNotOK returns how many of the validators are currently in NOT OK
+// state according to their latest recorded status.
+func (s *ClickhouseService) CountNotOK(adnls []string) (int, error) {
+	if len(adnls) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(adnls))
+	params := make([]interface{}, len(adnls))
+	for i, adnl := range adnls {
+		placeholders[i] = "?"
+		params[i] = adnl
+	}
+
+	query := fmt.Sprintf(`
+		SELECT countIf(last_status IN ('not ok', 'acknowledged', 'missing'))
+		FROM (
+			SELECT adnl_addr, argMax(status, timestamp) AS last_status
+			FROM validator_status_history
+			WHERE adnl_addr IN (%s)
+			GROUP BY adnl_addr
+		)
+	`, strings.Join(placeholders, ","))
+
+	ctx := context.Background()
+	var count uint64
+	if err := s.DB.QueryRow(ctx, query, params...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+// GetValidatorsList summarizes every validator with samples in the range:
+// its entry from the latest cycle in the range, current status, average
+// efficiency and time spent below the threshold.
+func (s *ClickhouseService) GetValidatorsList(from, to time.Time, cycleID uint32, threshold float64, cacheService *CacheService) ([]ValidatorListItem, error) {
+	fromRounded, toRounded := roundTimeRange(from, to)
+	cacheKey := fmt.Sprintf("ValidatorsList:%d:%d:%d:%g", fromRounded.Unix(), toRounded.Unix(), cycleID, threshold)
+
+	var list []ValidatorListItem
+	found, err := cacheService.GetCachedData(cacheKey, &list)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return list, nil
+	}
+
+	list, err = s.fetchValidatorsList(fromRounded, toRounded, cycleID, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cacheService.CacheData(cacheKey, list, 5*time.Minute); err != nil {
+		log.Printf("Error caching validators list: %v", err)
+	}
+	return list, nil
+}
+
+func (s *ClickhouseService) fetchValidatorsList(from, to time.Time, cycleID uint32, threshold float64) ([]ValidatorListItem, error) {
+	cycleQuery := ""
+	params := []interface{}{
+		int64(SLAGapThreshold().Seconds()), int64(ScrapeInterval().Seconds()), threshold,
+		from, to, from, to,
+	}
+	if cycleID != 0 {
+		cycleQuery = "AND cycle_id = ?"
+		params = append(params, cycleID)
+	}
+
+	// A sample followed by a data gap longer than SLAGapThreshold only
+	// counts for its own scrape interval. Acknowledgements are not statuses.
+	query := fmt.Sprintf(`
+		SELECT
+			e.adnl_addr,
+			v.wallet_address,
+			e.last_cycle,
+			v."index",
+			v.stake,
+			v.weight,
+			st.status,
+			e.avg_efficiency,
+			e.seconds_below
+		FROM (
+			SELECT
+				adnl_addr,
+				AVG(efficiency) AS avg_efficiency,
+				sumIf(if(gap > ?, ?, gap), efficiency < ?) AS seconds_below,
+				max(cycle_id) AS last_cycle
+			FROM (
+				SELECT
+					adnl_addr,
+					efficiency,
+					cycle_id,
+					toInt64(leadInFrame(toUnixTimestamp(timestamp), 1, toUnixTimestamp(timestamp))
+						OVER (PARTITION BY adnl_addr ORDER BY timestamp ROWS BETWEEN CURRENT ROW AND 1 FOLLOWING))
+						- toInt64(toUnixTimestamp(timestamp)) AS gap
+				FROM validator_efficiency
+				WHERE
+					date >= toDate(?) AND date <= toDate(?)
+					AND timestamp >= ? AND timestamp <= ?
+					%s
+			)
+			GROUP BY adnl_addr
+		) AS e
+		LEFT JOIN (
+			SELECT adnl_addr, cycle_id, wallet_address, "index", stake, weight
+			FROM validators FINAL
+		) AS v ON v.adnl_addr = e.adnl_addr AND v.cycle_id = e.last_cycle
+		LEFT JOIN (
+			SELECT adnl_addr, argMaxIf(status, timestamp, status != 'acknowledged') AS status
+			FROM validator_status_history
+			GROUP BY adnl_addr
+		) AS st ON st.adnl_addr = e.adnl_addr
+	`, cycleQuery)
+
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]ValidatorListItem, 0)
+	for rows.Next() {
+		var item ValidatorListItem
+		var status string
+		if err := rows.Scan(
+			&item.ADNLAddr,
+			&item.WalletAddress,
+			&item.CycleID,
+			&item.Index,
+			&item.Stake,
+			&item.Weight,
+			&status,
+			&item.AvgEfficiency,
+			&item.SecondsBelowThreshold,
+		); err != nil {
+			return nil, err
+		}
+		item.Status = ValidatorStatus(status)
+		list = append(list, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetValidatorsSeries returns the efficiency series of the given validators,
+// in the same shape as GetValidatorsStatuses.
+func (s *ClickhouseService) GetValidatorsSeries(adnls []string, from, to time.Time, cycleID uint32, cacheService *CacheService) (map[string]map[uint32]float64, error) {
+	if len(adnls) == 0 {
+		return map[string]map[uint32]float64{}, nil
+	}
+	fromRounded, toRounded := roundTimeRange(from, to)
+	return s.getCachedStatuses(adnls, fromRounded, toRounded, cycleID, cacheService)
+}
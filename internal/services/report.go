@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+func (s *ClickhouseService) GetCycleReport(cycleID uint32, threshold float64, cacheService *CacheService) (*CycleReport, error) {
+	cacheKey := fmt.Sprintf("CycleReport:%d:%g", cycleID, threshold)
+
+	var report CycleReport
+	found, err := cacheService.GetCachedData(cacheKey, &report)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &report, nil
+	}
+
+	info, err := s.GetCycleInfo(cycleID)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, nil
+	}
+
+	rules, err := s.GetFineRules(cacheService)
+	if err != nil {
+		log.Printf("Failed to get fine rules, using defaults: %v", err)
+	}
+
+	validators, err := s.fetchCycleReportFromDB(cycleID, info, threshold, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	report = CycleReport{
+		CycleID:    cycleID,
+		UtimeSince: info.UtimeSince,
+		UtimeUntil: info.UtimeUntil,
+		Threshold:  threshold,
+		Validators: validators,
+	}
+
+	// Reports of finished cycles don't change anymore, so they can live longer.
+	ttl := 5 * time.Minute
+	if time.Now().Unix() >= info.UtimeUntil {
+		ttl = time.Hour
+	}
+	if err := cacheService.CacheData(cacheKey, report, ttl); err != nil {
+		log.Printf("Error caching cycle report for %d: %v", cycleID, err)
+	}
+
+	return &report, nil
+}
+
+func (s *ClickhouseService) fetchCycleReportFromDB(cycleID uint32, info *CycleInfo, threshold float64, rules FineRules) ([]ValidatorCycleReport, error) {
+	since := time.Unix(info.UtimeSince, 0)
+	until := time.Unix(info.UtimeUntil, 0)
+
+	query := `
+		SELECT
+			v.adnl_addr,
+			v.wallet_address,
+			v."index",
+			e.samples,
+			e.avg_efficiency,
+			e.min_efficiency,
+			e.p5_efficiency,
+			e.seconds_below,
+			f.flips,
+			v.stake,
+			v.weight,
+			c.complaints,
+			c.fines,
+			c.fine_parts
+		FROM (SELECT * FROM validators FINAL WHERE cycle_id = ?) AS v
+		LEFT JOIN (
+			SELECT
+				adnl_addr,
+				count() AS samples,
+				AVG(efficiency) AS avg_efficiency,
+				min(efficiency) AS min_efficiency,
+				quantile(0.05)(efficiency) AS p5_efficiency,
+				sumIf(gap, efficiency < ?) AS seconds_below
+			FROM (
+				SELECT
+					adnl_addr,
+					efficiency,
+					toInt64(leadInFrame(toUnixTimestamp(timestamp), 1, toUnixTimestamp(timestamp))
+						OVER (PARTITION BY adnl_addr ORDER BY timestamp ROWS BETWEEN CURRENT ROW AND 1 FOLLOWING))
+						- toInt64(toUnixTimestamp(timestamp)) AS gap
+				FROM validator_efficiency
+				WHERE
+					date >= toDate(?) AND date <= toDate(?)
+					AND cycle_id = ?
+			)
+			GROUP BY adnl_addr
+		) AS e ON e.adnl_addr = v.adnl_addr
+		LEFT JOIN (
+			SELECT adnl_addr, count() AS flips
+			FROM validator_status_history
+			WHERE timestamp >= ? AND timestamp < ? AND status != 'acknowledged'
+			GROUP BY adnl_addr
+		) AS f ON f.adnl_addr = v.adnl_addr
+		LEFT JOIN (
+			SELECT
+				adnl_addr,
+				uniqExact(hash) AS complaints,
+				sum(suggested_fine) AS fines,
+				sum(suggested_fine_part) AS fine_parts
+			FROM complaints FINAL
+			WHERE cycle_id = ?
+			GROUP BY adnl_addr
+		) AS c ON c.adnl_addr = v.adnl_addr
+		ORDER BY v.stake DESC
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, cycleID, threshold, since, until, cycleID, since, until, cycleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []ValidatorCycleReport
+	for rows.Next() {
+		var row ValidatorCycleReport
+		var complaintFines FineRules
+		if err := rows.Scan(
+			&row.ADNLAddr,
+			&row.WalletAddress,
+			&row.Index,
+			&row.Samples,
+			&row.AvgEfficiency,
+			&row.MinEfficiency,
+			&row.P5Efficiency,
+			&row.SecondsBelowThreshold,
+			&row.StatusFlips,
+			&row.Stake,
+			&row.Weight,
+			&row.Complaints,
+			&complaintFines.BaseFine,
+			&complaintFines.FinePart,
+		); err != nil {
+			return nil, err
+		}
+
+		var fines int64
+		if row.Complaints > 0 {
+			fines = complaintFines.Fine(row.Stake)
+		}
+		exposure := EstimateExposure(row.Weight, info.TotalWeight, row.Stake, row.AvgEfficiency, fines, rules)
+		row.ExpectedRewardShare = exposure.ExpectedRewardShare
+		if row.Samples == 0 {
+			// Without samples the efficiency reads as 0; only filed
+			// complaints say anything about the exposure.
+			row.NoData = true
+			row.EstimatedExposureTON = float64(fines) / nanoTON
+		} else {
+			row.EffectiveRewardShare = exposure.EffectiveRewardShare
+			row.RewardShareLoss = exposure.RewardShareLoss
+			row.EstimatedExposureTON = exposure.EstimatedExposureTON
+		}
+		report = append(report, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+const (
+	DefaultScrapeInterval = time.Minute
+	// slaGapIntervals is how many scrape intervals may pass between two
+	// scoreboard samples before the pause counts as unknown time.
+	slaGapIntervals = 3
+)
+
+// ScrapeInterval is the cadence at which every active cycle is scraped,
+// set with SCRAPE_INTERVAL_SECONDS.
+func ScrapeInterval() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("SCRAPE_INTERVAL_SECONDS"))
+	if err != nil || seconds <= 0 {
+		return DefaultScrapeInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// SLAGapThreshold is the longest pause between two scoreboard samples that
+// is still treated as continuous data.
+func SLAGapThreshold() time.Duration {
+	return slaGapIntervals * ScrapeInterval()
+}
+
+type StatusEvent struct {
+	Timestamp time.Time
+	Status    ValidatorStatus
+}
+
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (s *ClickhouseService) GetValidatorSLA(adnl string, from, to time.Time, cacheService *CacheService) (*ValidatorSLA, error) {
+	fromRounded, toRounded := roundTimeRange(from, to)
+	if !toRounded.After(fromRounded) {
+		return nil, fmt.Errorf("invalid date interval")
+	}
+	cacheKey := fmt.Sprintf("ValidatorSLA:%s:%d:%d", adnl, fromRounded.Unix(), toRounded.Unix())
+
+	var sla ValidatorSLA
+	found, err := cacheService.GetCachedData(cacheKey, &sla)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &sla, nil
+	}
+
+	initial, events, err := s.fetchStatusEvents(adnl, fromRounded, toRounded)
+	if err != nil {
+		return nil, err
+	}
+
+	gaps, err := s.fetchDataGaps(adnl, fromRounded, toRounded)
+	if err != nil {
+		return nil, err
+	}
+
+	sla = ComputeSLA(fromRounded, toRounded, initial, events, gaps)
+	sla.ADNLAddr = adnl
+
+	if err := cacheService.CacheData(cacheKey, sla, 5*time.Minute); err != nil {
+		log.Printf("Error caching SLA for %s: %v", adnl, err)
+	}
+
+	return &sla, nil
+}
+
+// fetchStatusEvents returns the status in effect at `from` and every status
+// change recorded inside the range.
+func (s *ClickhouseService) fetchStatusEvents(adnl string, from, to time.Time) (ValidatorStatus, []StatusEvent, error) {
+	ctx := context.Background()
+	initial := StatusUnknown
+
+	rows, err := s.DB.Query(ctx, `
+		SELECT status
+		FROM validator_status_history
+		WHERE adnl_addr = ? AND timestamp < ?
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`, adnl, from)
+	if err != nil {
+		return initial, nil, err
+	}
+	if rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			rows.Close()
+			return initial, nil, err
+		}
+		initial = ValidatorStatus(status)
+	}
+	rows.Close()
+
+	rows, err = s.DB.Query(ctx, `
+		SELECT timestamp, status
+		FROM validator_status_history
+		WHERE adnl_addr = ? AND timestamp >= ? AND timestamp < ?
+		ORDER BY timestamp
+	`, adnl, from, to)
+	if err != nil {
+		return initial, nil, err
+	}
+	defer rows.Close()
+
+	var events []StatusEvent
+	for rows.Next() {
+		var event StatusEvent
+		var status string
+		if err := rows.Scan(&event.Timestamp, &status); err != nil {
+			return initial, nil, err
+		}
+		event.Status = ValidatorStatus(status)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return initial, nil, err
+	}
+
+	return initial, events, nil
+}
+
+// fetchDataGaps returns the parts of the range not covered by scoreboard
+// samples, including a leading and a trailing gap.
+func (s *ClickhouseService) fetchDataGaps(adnl string, from, to time.Time) ([]TimeRange, error) {
+	query := `
+		SELECT prev_ts, ts
+		FROM (
+			SELECT
+				ts,
+				lagInFrame(ts, 1, toInt64(toUnixTimestamp(?))) OVER (ORDER BY ts ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) AS prev_ts
+			FROM (
+				SELECT DISTINCT toInt64(toUnixTimestamp(timestamp)) AS ts
+				FROM validator_efficiency
+				WHERE
+					date >= toDate(?) AND date <= toDate(?)
+					AND timestamp >= ? AND timestamp <= ?
+					AND adnl_addr = ?
+				UNION ALL
+				SELECT toInt64(toUnixTimestamp(?)) AS ts
+			)
+		)
+		WHERE ts - prev_ts > ?
+		ORDER BY prev_ts
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, from, from, to, from, to, adnl, to, int64(SLAGapThreshold().Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gaps []TimeRange
+	for rows.Next() {
+		var gapFrom, gapTo int64
+		if err := rows.Scan(&gapFrom, &gapTo); err != nil {
+			return nil, err
+		}
+		gaps = append(gaps, TimeRange{From: time.Unix(gapFrom, 0), To: time.Unix(gapTo, 0)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return gaps, nil
+}
+
+// ComputeSLA splits [from, to) into ok, not ok, acknowledged and unknown time.
+// Time covered by a data gap is always unknown, whatever the status was.
+// An incident starts when the validator leaves ok for not ok inside the
+// range; MTTR averages the incidents that were also resolved inside it, and
+// MTBF is the ok time divided by the number of incidents.
+func ComputeSLA(from, to time.Time, initial ValidatorStatus, events []StatusEvent, gaps []TimeRange) ValidatorSLA {
+	sla := ValidatorSLA{From: from.Unix(), To: to.Unix()}
+	total := to.Sub(from)
+	if total <= 0 {
+		return sla
+	}
+
+	cuts := []time.Time{from, to}
+	for _, event := range events {
+		cuts = append(cuts, event.Timestamp)
+	}
+	for _, gap := range gaps {
+		cuts = append(cuts, gap.From, gap.To)
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].Before(cuts[j]) })
+
+	statusAt := func(t time.Time) ValidatorStatus {
+		status := initial
+		for _, event := range events {
+			if event.Timestamp.After(t) {
+				break
+			}
+			status = event.Status
+		}
+		return status
+	}
+	inGap := func(t time.Time) bool {
+		for _, gap := range gaps {
+			if !t.Before(gap.From) && t.Before(gap.To) {
+				return true
+			}
+		}
+		return false
+	}
+
+	durations := make(map[ValidatorStatus]time.Duration)
+	var unknown time.Duration
+	for i := 1; i < len(cuts); i++ {
+		start, end := cuts[i-1], cuts[i]
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.After(start) {
+			continue
+		}
+
+		middle := start.Add(end.Sub(start) / 2)
+		status := normalizeSLAStatus(statusAt(middle))
+		if inGap(middle) || status == StatusUnknown {
+			unknown += end.Sub(start)
+			continue
+		}
+		durations[status] += end.Sub(start)
+	}
+
+	var repairs []time.Duration
+	var incidentStart *time.Time
+	previous := normalizeSLAStatus(initial)
+	for i, event := range events {
+		status := normalizeSLAStatus(event.Status)
+		inIncident := previous == StatusNotOK || previous == StatusAcknowledged
+		switch {
+		case status == StatusNotOK && !inIncident:
+			sla.Incidents++
+			incidentStart = &events[i].Timestamp
+		case status == StatusOK && inIncident && incidentStart != nil:
+			repairs = append(repairs, event.Timestamp.Sub(*incidentStart))
+			incidentStart = nil
+		}
+		previous = status
+	}
+
+	percent := func(d time.Duration) float64 {
+		return float64(d) / float64(total) * 100
+	}
+	sla.OKPercent = percent(durations[StatusOK])
+	sla.NotOKPercent = percent(durations[StatusNotOK])
+	sla.AcknowledgedPercent = percent(durations[StatusAcknowledged])
+	sla.UnknownPercent = percent(unknown)
+
+	if len(repairs) > 0 {
+		var sum time.Duration
+		for _, repair := range repairs {
+			sum += repair
+		}
+		sla.MTTRSeconds = (sum / time.Duration(len(repairs))).Seconds()
+	}
+	if sla.Incidents > 0 {
+		sla.MTBFSeconds = durations[StatusOK].Seconds() / float64(sla.Incidents)
+	}
+
+	return sla
+}
+
+// normalizeSLAStatus counts a missing validator as not ok: it is known to be
+// in the cycle and not producing blocks.
+func normalizeSLAStatus(status ValidatorStatus) ValidatorStatus {
+	if status == StatusMissing {
+		return StatusNotOK
+	}
+	return status
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+func TestComputeSLA(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(100 * time.Minute)
+	at := func(minutes int) time.Time {
+		return from.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	tests := []struct {
+		name    string
+		initial ValidatorStatus
+		events  []StatusEvent
+		gaps    []TimeRange
+		want    ValidatorSLA
+	}{
+		{
+			name:    "always ok",
+			initial: StatusOK,
+			want:    ValidatorSLA{OKPercent: 100},
+		},
+		{
+			name:    "unknown before the first status",
+			initial: StatusUnknown,
+			events:  []StatusEvent{{at(25), StatusOK}},
+			want:    ValidatorSLA{OKPercent: 75, UnknownPercent: 25},
+		},
+		{
+			name:    "resolved incident",
+			initial: StatusOK,
+			events:  []StatusEvent{{at(20), StatusNotOK}, {at(30), StatusOK}},
+			want: ValidatorSLA{
+				OKPercent:    90,
+				NotOKPercent: 10,
+				Incidents:    1,
+				MTTRSeconds:  600,
+				MTBFSeconds:  90 * 60,
+			},
+		},
+		{
+			name:    "acknowledged incident",
+			initial: StatusOK,
+			events:  []StatusEvent{{at(10), StatusNotOK}, {at(20), StatusAcknowledged}, {at(40), StatusOK}},
+			want: ValidatorSLA{
+				OKPercent:           70,
+				NotOKPercent:        10,
+				AcknowledgedPercent: 20,
+				Incidents:           1,
+				MTTRSeconds:         1800,
+				MTBFSeconds:         70 * 60,
+			},
+		},
+		{
+			name:    "unresolved incident",
+			initial: StatusOK,
+			events:  []StatusEvent{{at(90), StatusNotOK}},
+			want: ValidatorSLA{
+				OKPercent:    90,
+				NotOKPercent: 10,
+				Incidents:    1,
+				MTBFSeconds:  90 * 60,
+			},
+		},
+		{
+			name:    "missing counts as not ok",
+			initial: StatusMissing,
+			want:    ValidatorSLA{NotOKPercent: 100},
+		},
+		{
+			name:    "gaps are unknown",
+			initial: StatusOK,
+			events:  []StatusEvent{{at(20), StatusNotOK}, {at(30), StatusOK}},
+			gaps:    []TimeRange{{at(25), at(35)}, {at(95), at(110)}},
+			want: ValidatorSLA{
+				OKPercent:      80,
+				NotOKPercent:   5,
+				UnknownPercent: 15,
+				Incidents:      1,
+				MTTRSeconds:    600,
+				MTBFSeconds:    80 * 60,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeSLA(from, to, tt.initial, tt.events, tt.gaps)
+			tt.want.From, tt.want.To = from.Unix(), to.Unix()
+
+			floats := []struct {
+				field     string
+				got, want float64
+			}{
+				{"OKPercent", got.OKPercent, tt.want.OKPercent},
+				{"NotOKPercent", got.NotOKPercent, tt.want.NotOKPercent},
+				{"AcknowledgedPercent", got.AcknowledgedPercent, tt.want.AcknowledgedPercent},
+				{"UnknownPercent", got.UnknownPercent, tt.want.UnknownPercent},
+				{"MTTRSeconds", got.MTTRSeconds, tt.want.MTTRSeconds},
+				{"MTBFSeconds", got.MTBFSeconds, tt.want.MTBFSeconds},
+			}
+			for _, f := range floats {
+				if math.Abs(f.got-f.want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", f.field, f.got, f.want)
+				}
+			}
+			if got.Incidents != tt.want.Incidents {
+				t.Errorf("Incidents = %d, want %d", got.Incidents, tt.want.Incidents)
+			}
+			if got.From != tt.want.From || got.To != tt.want.To {
+				t.Errorf("range = [%d, %d), want [%d, %d)", got.From, got.To, tt.want.From, tt.want.To)
+			}
+		})
+	}
+}
+
+func TestComputeSLAEmptyRange(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := ComputeSLA(from, from, StatusOK, nil, nil)
+	if got != (ValidatorSLA{From: from.Unix(), To: from.Unix()}) {
+		t.Errorf("ComputeSLA() = %+v, want an empty SLA", got)
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+const (
+	defaultStakeChangePercent = 10
+	defaultStakeMissedCycles  = 2
+)
+
+// StakeAlertConfig controls which stake and participation changes are
+// reported.
+type StakeAlertConfig struct {
+	// ChangePercent is the minimal stake change between cycles, in percent.
+	ChangePercent float64
+	// MissedCycles is how many cycles in a row a validator has to miss to
+	// count as having left the elections. Validators that take part in
+	// every other cycle stay below the default of 2.
+	MissedCycles int
+}
+
+func GetStakeAlertConfig() StakeAlertConfig {
+	config := StakeAlertConfig{
+		ChangePercent: defaultStakeChangePercent,
+		MissedCycles:  defaultStakeMissedCycles,
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("STAKE_CHANGE_ALERT_PERCENT"), 64); err == nil && v > 0 {
+		config.ChangePercent = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("STAKE_ALERT_MISSED_CYCLES")); err == nil && v > 0 {
+		config.MissedCycles = v
+	}
+	return config
+}
+
+// StakeChangePercent is the change from previous to current in percent of
+// previous.
+func StakeChangePercent(previous, current int64) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return float64(current-previous) / float64(previous) * 100
+}
+
+// IsSignificantStakeChange reports whether the change reaches the
+// configured percentage.
+func (c StakeAlertConfig) IsSignificantStakeChange(previous, current int64) bool {
+	return previous != 0 && math.Abs(StakeChangePercent(previous, current)) >= c.ChangePercent
+}
+
+// GetStakeHistory returns the validator's entries in the last `limit`
+// cycles, oldest first, including the cycles it missed.
+func (s *ClickhouseService) GetStakeHistory(adnl string, limit int, cacheService *CacheService) ([]StakeHistoryEntry, error) {
+	cacheKey := fmt.Sprintf("StakeHistory:%s:%d", adnl, limit)
+	var history []StakeHistoryEntry
+	found, err := cacheService.GetCachedData(cacheKey, &history)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return history, nil
+	}
+
+	history, err = s.fetchStakeHistory(adnl, limit)
+	if err != nil {
+		return nil, err
+	}
+	markStakeEvents(history, GetStakeAlertConfig())
+
+	if err := cacheService.CacheData(cacheKey, history, 10*time.Minute); err != nil {
+		log.Printf("Error caching stake history of %s: %v", adnl, err)
+	}
+	return history, nil
+}
+
+func (s *ClickhouseService) fetchStakeHistory(adnl string, limit int) ([]StakeHistoryEntry, error) {
+	query := `
+		SELECT
+			ci.cycle_id,
+			toInt64(toUnixTimestamp(ci.utime_since)),
+			toInt64(toUnixTimestamp(ci.utime_until)),
+			v.cycle_id != 0,
+			v.stake,
+			v.weight,
+			v.max_factor
+		FROM (
+			SELECT cycle_id, utime_since, utime_until
+			FROM cycles_info FINAL
+			ORDER BY cycle_id DESC
+			LIMIT ?
+		) AS ci
+		LEFT JOIN (
+			SELECT cycle_id, stake, weight, max_factor
+			FROM validators FINAL
+			WHERE adnl_addr = ?
+		) AS v ON v.cycle_id = ci.cycle_id
+		ORDER BY ci.cycle_id
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, limit, adnl)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]StakeHistoryEntry, 0, limit)
+	for rows.Next() {
+		var entry StakeHistoryEntry
+		if err := rows.Scan(&entry.CycleID, &entry.UtimeSince, &entry.UtimeUntil, &entry.Participated, &entry.Stake, &entry.Weight, &entry.MaxFactor); err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// markStakeEvents compares each entry with the last cycle the validator
+// took part in before it and marks the changes that would be alerted.
+func markStakeEvents(history []StakeHistoryEntry, config StakeAlertConfig) {
+	last := -1
+	for i := range history {
+		entry := &history[i]
+		if !entry.Participated {
+			if last >= 0 && i-last == config.MissedCycles {
+				entry.Event = StakeEventLeft
+			}
+			continue
+		}
+		if last >= 0 {
+			previous := history[last].Stake
+			entry.StakeChange = entry.Stake - previous
+			entry.ChangePercent = StakeChangePercent(previous, entry.Stake)
+			if i-last-1 >= config.MissedCycles {
+				entry.Event = StakeEventRejoined
+			} else if config.IsSignificantStakeChange(previous, entry.Stake) {
+				entry.Event = StakeEventChanged
+			}
+		}
+		last = i
+	}
+}
+
+// GetPreviousCycleIDs returns up to `limit` cycles before the given one,
+// newest first.
+func (s *ClickhouseService) GetPreviousCycleIDs(cycleID uint32, limit int) ([]uint32, error) {
+	query := `
+		SELECT cycle_id
+		FROM cycles_info FINAL
+		WHERE cycle_id < ?
+		ORDER BY cycle_id DESC
+		LIMIT ?
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, cycleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint32
+	for rows.Next() {
+		var id uint32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetCycleADNLs returns the validators elected in the cycle.
+func (s *ClickhouseService) GetCycleADNLs(cycleID uint32) ([]string, error) {
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, `SELECT adnl_addr FROM validators FINAL WHERE cycle_id = ?`, cycleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var adnls []string
+	for rows.Next() {
+		var adnl string
+		if err := rows.Scan(&adnl); err != nil {
+			return nil, err
+		}
+		adnls = append(adnls, adnl)
+	}
+	return adnls, rows.Err()
+}
+
+// GetLastParticipation returns each validator's entry from the latest cycle
+// before the given one it was elected in. Validators never elected before
+// are left out.
+func (s *ClickhouseService) GetLastParticipation(adnls []string, beforeCycleID uint32) (map[string]ValidatorInfo, error) {
+	result := make(map[string]ValidatorInfo)
+	if len(adnls) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(adnls))
+	params := []interface{}{beforeCycleID}
+	for i, adnl := range adnls {
+		placeholders[i] = "?"
+		params = append(params, adnl)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			adnl_addr,
+			max(cycle_id),
+			argMax(pubkey, cycle_id),
+			argMax(weight, cycle_id),
+			argMax("index", cycle_id),
+			argMax(stake, cycle_id),
+			argMax(max_factor, cycle_id),
+			argMax(wallet_address, cycle_id)
+		FROM validators FINAL
+		WHERE cycle_id < ? AND adnl_addr IN (%s)
+		GROUP BY adnl_addr
+	`, strings.Join(placeholders, ","))
+
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var info ValidatorInfo
+		if err := rows.Scan(&info.ADNLAddr, &info.CycleID, &info.PubKey, &info.Weight, &info.Index, &info.Stake, &info.MaxFactor, &info.WalletAddress); err != nil {
+			return nil, err
+		}
+		result[info.ADNLAddr] = info
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "validators-health/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// EventStreamKey is the Redis stream of live validator events.
+	EventStreamKey = "validator_events"
+	// eventStreamMaxLen keeps roughly a day of samples for resuming clients.
+	eventStreamMaxLen = 500000
+	eventReadCount    = 1000
+)
+
+// PublishEvents appends the events to the live event stream.
+func (c *CacheService) PublishEvents(events []StreamEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	pipe := c.RedisClient.Pipeline()
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: EventStreamKey,
+			MaxLen: eventStreamMaxLen,
+			Approx: true,
+			Values: map[string]interface{}{"event": data},
+		})
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LastEventID returns the position of the newest event, or "0-0" when the
+// stream is empty.
+func (c *CacheService) LastEventID(ctx context.Context) (string, error) {
+	messages, err := c.RedisClient.XRevRangeN(ctx, EventStreamKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "0-0", nil
+	}
+	return messages[0].ID, nil
+}
+
+// ReadEvents returns events after the given position, waiting up to block
+// for new ones.
+func (c *CacheService) ReadEvents(ctx context.Context, after string, block time.Duration) ([]StreamEvent, error) {
+	streams, err := c.RedisClient.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{EventStreamKey, after},
+		Count:   eventReadCount,
+		Block:   block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []StreamEvent
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			data, ok := message.Values["event"].(string)
+			if !ok {
+				continue
+			}
+			var event StreamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				continue
+			}
+			event.ID = message.ID
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
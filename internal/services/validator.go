@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	. "validators-health/internal/models"
+)
+
+// GetCurrentStatus returns the validator's latest status change, or nil if
+// none was recorded. Acknowledgements are skipped: they record who handled
+// an alert, not the validator's health. The status comes from the same
+// cached history the detail endpoint returns, so the two agree.
+func (s *ClickhouseService) GetCurrentStatus(adnl string, cacheService *CacheService) (*ValidatorStatusHistory, error) {
+	history, err := s.GetStatusHistory(adnl, cacheService)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range history {
+		if ValidatorStatus(record.Status) != StatusAcknowledged {
+			return &record, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetValidatorCycles returns the validator's stats for the last `limit`
+// cycles it was elected in, newest first.
+func (s *ClickhouseService) GetValidatorCycles(adnl string, limit int, threshold float64, cacheService *CacheService) ([]ValidatorCycleStats, error) {
+	cacheKey := fmt.Sprintf("ValidatorCycles:%s:%d:%g", adnl, limit, threshold)
+	var cycles []ValidatorCycleStats
+	found, err := cacheService.GetCachedData(cacheKey, &cycles)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return cycles, nil
+	}
+
+	cycles, err = s.fetchValidatorCycles(adnl, limit, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cacheService.CacheData(cacheKey, cycles, 5*time.Minute); err != nil {
+		log.Printf("Error caching cycles of validator %s: %v", adnl, err)
+	}
+	return cycles, nil
+}
+
+func (s *ClickhouseService) fetchValidatorCycles(adnl string, limit int, threshold float64) ([]ValidatorCycleStats, error) {
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, `
+		SELECT
+			v.cycle_id,
+			toInt64(toUnixTimestamp(ci.utime_since)),
+			toInt64(toUnixTimestamp(ci.utime_until)),
+			v."index",
+			v.stake,
+			v.weight,
+			v.max_factor
+		FROM (
+			SELECT cycle_id, "index", stake, weight, max_factor
+			FROM validators FINAL
+			WHERE adnl_addr = ?
+		) AS v
+		LEFT JOIN (
+			SELECT cycle_id, utime_since, utime_until
+			FROM cycles_info FINAL
+		) AS ci ON ci.cycle_id = v.cycle_id
+		ORDER BY v.cycle_id DESC
+		LIMIT ?
+	`, adnl, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cycles := make([]ValidatorCycleStats, 0, limit)
+	byID := make(map[uint32]*ValidatorCycleStats)
+	for rows.Next() {
+		var cycle ValidatorCycleStats
+		if err := rows.Scan(&cycle.CycleID, &cycle.UtimeSince, &cycle.UtimeUntil, &cycle.Index, &cycle.Stake, &cycle.Weight, &cycle.MaxFactor); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		cycles = append(cycles, cycle)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(cycles) == 0 {
+		return cycles, nil
+	}
+
+	placeholders := make([]string, len(cycles))
+	cycleParams := make([]interface{}, len(cycles))
+	since := cycles[0].UtimeSince
+	for i := range cycles {
+		byID[cycles[i].CycleID] = &cycles[i]
+		placeholders[i] = "?"
+		cycleParams[i] = cycles[i].CycleID
+		if cycles[i].UtimeSince > 0 && cycles[i].UtimeSince < since {
+			since = cycles[i].UtimeSince
+		}
+	}
+	inCycles := strings.Join(placeholders, ",")
+
+	params := append([]interface{}{threshold, adnl, time.Unix(since, 0)}, cycleParams...)
+	rows, err = s.DB.Query(ctx, fmt.Sprintf(`
+		SELECT
+			cycle_id,
+			avg(efficiency),
+			min(efficiency),
+			countIf(efficiency < ?) / count() * 100,
+			count()
+		FROM validator_efficiency
+		WHERE adnl_addr = ? AND date >= toDate(?) AND cycle_id IN (%s)
+		GROUP BY cycle_id
+	`, inCycles), params...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var cycleID uint32
+		var avg, min, below float64
+		var samples uint64
+		if err := rows.Scan(&cycleID, &avg, &min, &below, &samples); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if cycle, ok := byID[cycleID]; ok {
+			cycle.AvgEfficiency = avg
+			cycle.MinEfficiency = min
+			cycle.BelowThresholdPercent = below
+			cycle.Samples = samples
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	params = append([]interface{}{adnl}, cycleParams...)
+	rows, err = s.DB.Query(ctx, fmt.Sprintf(`
+		SELECT cycle_id, uniqExact(hash)
+		FROM complaints FINAL
+		WHERE adnl_addr = ? AND cycle_id IN (%s)
+		GROUP BY cycle_id
+	`, inCycles), params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cycleID uint32
+		var complaints uint64
+		if err := rows.Scan(&cycleID, &complaints); err != nil {
+			return nil, err
+		}
+		if cycle, ok := byID[cycleID]; ok {
+			cycle.Complaints = complaints
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cycles, nil
+}
+
+// GetValidatorComplaints returns the latest complaints against the validator.
+func (s *ClickhouseService) GetValidatorComplaints(adnl string, limit int) ([]ValidatorComplaint, error) {
+	query := `
+		SELECT cycle_id, election_id, hash, description, created_time, severity,
+			suggested_fine, suggested_fine_part, approved_percent, is_passed
+		FROM complaints FINAL
+		WHERE adnl_addr = ?
+		ORDER BY created_time DESC
+		LIMIT ?
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, adnl, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	complaints := make([]ValidatorComplaint, 0)
+	for rows.Next() {
+		var c ValidatorComplaint
+		if err := rows.Scan(&c.CycleID, &c.ElectionID, &c.Hash, &c.Description, &c.CreatedTime, &c.Severity,
+			&c.SuggestedFine, &c.SuggestedFinePart, &c.ApprovedPercent, &c.IsPassed); err != nil {
+			return nil, err
+		}
+		complaints = append(complaints, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return complaints, nil
+}
+
+// CountSubscribers returns how many chats get the validator's alerts,
+// directly under any of its ADNLs or through one of the given groups.
+func (c *CacheService) CountSubscribers(adnls []string, groups []ValidatorGroup) (int, error) {
+	var keys []string
+	for _, adnl := range adnls {
+		keys = append(keys, fmt.Sprintf("subscription_%s", adnl))
+	}
+	for _, group := range groups {
+		keys = append(keys, fmt.Sprintf("group_subscription_%s", group.Name))
+	}
+	chats, err := c.RedisClient.SUnion(context.Background(), keys...).Result()
+	if err != nil {
+		return 0, err
+	}
+	return len(chats), nil
+}
+
+// GetValidatorADNLs returns the last validator ADNL seen in the scoreboard
+// for each of the given ADNL addresses. Addresses without any sample are
+// left out.
+func (s *ClickhouseService) GetValidatorADNLs(adnls []string) (map[string]string, error) {
+	result := make(map[string]string)
+	if len(adnls) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(adnls))
+	params := make([]interface{}, len(adnls))
+	for i, adnl := range adnls {
+		placeholders[i] = "?"
+		params[i] = adnl
+	}
+
+	query := fmt.Sprintf(`
+		SELECT adnl_addr, argMax(validator_adnl, timestamp)
+		FROM validator_efficiency
+		WHERE adnl_addr IN (%s)
+		GROUP BY adnl_addr
+	`, strings.Join(placeholders, ","))
+
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var adnlAddr, validatorADNL string
+		if err := rows.Scan(&adnlAddr, &validatorADNL); err != nil {
+			return nil, err
+		}
+		if validatorADNL != "" {
+			result[adnlAddr] = validatorADNL
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
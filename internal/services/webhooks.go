@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	. "validators-health/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	webhooksKey = "webhooks"
+	// WebhookQueueKey is a sorted set of pending deliveries scored by the
+	// unix time of their next attempt.
+	WebhookQueueKey = "webhook_queue"
+	// webhookLease is how long a claimed delivery is hidden from other
+	// pollers. Deliveries not acknowledged in time are claimed again.
+	webhookLease = time.Minute
+)
+
+// WebhookTask is a pending delivery of an event to a webhook.
+type WebhookTask struct {
+	WebhookID string       `json:"webhook_id"`
+	Event     WebhookEvent `json:"event"`
+	Attempt   int          `json:"attempt"`
+
+	// member is the queue entry the task was claimed from.
+	member string
+}
+
+func (c *CacheService) SaveWebhook(webhook Webhook) error {
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	return c.RedisClient.HSet(context.Background(), webhooksKey, webhook.ID, data).Err()
+}
+
+// GetWebhook returns the webhook, or nil if there is none with this ID.
+func (c *CacheService) GetWebhook(id string) (*Webhook, error) {
+	data, err := c.RedisClient.HGet(context.Background(), webhooksKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook Webhook
+	if err := json.Unmarshal([]byte(data), &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (c *CacheService) ListWebhooks() ([]Webhook, error) {
+	items, err := c.RedisClient.HGetAll(context.Background(), webhooksKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]Webhook, 0, len(items))
+	for _, data := range items {
+		var webhook Webhook
+		if err := json.Unmarshal([]byte(data), &webhook); err != nil {
+			continue
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook and reports whether it existed. Its
+// pending deliveries are dropped when they come up.
+func (c *CacheService) DeleteWebhook(id string) (bool, error) {
+	removed, err := c.RedisClient.HDel(context.Background(), webhooksKey, id).Result()
+	return removed > 0, err
+}
+
+// ScheduleWebhookTask queues the delivery for the given time.
+func (c *CacheService) ScheduleWebhookTask(task WebhookTask, at time.Time) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return c.RedisClient.ZAdd(context.Background(), WebhookQueueKey, &redis.Z{
+		Score:  float64(at.Unix()),
+		Member: data,
+	}).Err()
+}
+
+// claimWebhookTasksScript moves due deliveries ARGV[3] seconds into the
+// future and returns them, so each is handed to one poller at a time.
+var claimWebhookTasksScript = redis.NewScript(`
+local items = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, item in ipairs(items) do
+	redis.call('ZADD', KEYS[1], ARGV[1] + ARGV[3], item)
+end
+return items
+`)
+
+// ClaimWebhookTasks takes up to limit deliveries that are due. Each task is
+// handed to one caller only, even with several replicas polling the queue.
+// Claimed tasks stay queued under a lease until AckWebhookTask removes them.
+func (c *CacheService) ClaimWebhookTasks(limit int64) ([]WebhookTask, error) {
+	items, err := claimWebhookTasksScript.Run(context.Background(), c.RedisClient,
+		[]string{WebhookQueueKey}, time.Now().Unix(), limit, int64(webhookLease.Seconds())).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []WebhookTask
+	for _, item := range items {
+		var task WebhookTask
+		if err := json.Unmarshal([]byte(item), &task); err != nil {
+			c.RedisClient.ZRem(context.Background(), WebhookQueueKey, item)
+			continue
+		}
+		task.member = item
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// AckWebhookTask removes a claimed delivery from the queue.
+func (c *CacheService) AckWebhookTask(task WebhookTask) error {
+	return c.RedisClient.ZRem(context.Background(), WebhookQueueKey, task.member).Err()
+}
+
+// InsertWebhookDelivery records a delivery attempt.
+func (s *ClickhouseService) InsertWebhookDelivery(delivery WebhookDelivery) error {
+	query := "INSERT INTO webhook_deliveries (timestamp, webhook_id, event_id, event_type, attempt, status_code, error, success, duration_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	ctx := context.Background()
+	err := s.DB.Exec(ctx, query,
+		delivery.Timestamp.Unix(),
+		delivery.WebhookID,
+		delivery.EventID,
+		string(delivery.EventType),
+		uint16(delivery.Attempt),
+		uint16(delivery.StatusCode),
+		delivery.Error,
+		delivery.Success,
+		uint32(delivery.DurationMs),
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting webhook delivery into ClickHouse: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns the webhook's most recent delivery attempts.
+func (s *ClickhouseService) GetWebhookDeliveries(webhookID string, limit int) ([]WebhookDelivery, error) {
+	query := `
+		SELECT timestamp, webhook_id, event_id, event_type, attempt, status_code, error, success, duration_ms
+		FROM webhook_deliveries
+		WHERE webhook_id = ?
+		ORDER BY timestamp DESC
+		LIMIT ?
+	`
+	ctx := context.Background()
+	rows, err := s.DB.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery WebhookDelivery
+		var eventType string
+		var attempt, statusCode uint16
+		var durationMs uint32
+		if err := rows.Scan(
+			&delivery.Timestamp,
+			&delivery.WebhookID,
+			&delivery.EventID,
+			&eventType,
+			&attempt,
+			&statusCode,
+			&delivery.Error,
+			&delivery.Success,
+			&durationMs,
+		); err != nil {
+			return nil, err
+		}
+		delivery.EventType = WebhookEventType(eventType)
+		delivery.Attempt = int(attempt)
+		delivery.StatusCode = int(statusCode)
+		delivery.DurationMs = int64(durationMs)
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
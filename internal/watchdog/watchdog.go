@@ -0,0 +1,144 @@
+package watchdog
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+	m "validators-health/internal/models"
+	"validators-health/internal/notifier"
+	"validators-health/internal/services"
+)
+
+const (
+	ingestionStatusKey           = "ingestion_status"
+	defaultIngestionLagThreshold = 10 * time.Minute
+	checkInterval                = time.Minute
+	// checkLockKey is held for checkLease by the replica running the check,
+	// so replicas whose tickers fire close together don't all alert.
+	checkLockKey = "ingestion_check_lock"
+	checkLease   = 50 * time.Second
+)
+
+type IngestionStatusInfo struct {
+	Stalled   bool      `json:"stalled"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Watchdog notices when the scrapper stops producing data, either because the
+// cycles API is failing or because no scoreboard samples are being stored.
+type Watchdog struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+	Notifier          *notifier.Notifier
+}
+
+func NewWatchdog(clickhouseService *services.ClickhouseService, cacheService *services.CacheService, n *notifier.Notifier) *Watchdog {
+	return &Watchdog{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+		Notifier:          n,
+	}
+}
+
+// lagThreshold is how old the newest sample or cycles fetch may get before
+// ingestion is considered stalled.
+func lagThreshold() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("INGESTION_LAG_THRESHOLD_SECONDS"))
+	if err != nil || seconds <= 0 {
+		return defaultIngestionLagThreshold
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (w *Watchdog) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			log.Println("Watchdog is shutting down.")
+			return
+		case <-ticker.C:
+			// Several replicas run this loop; only one of them checks.
+			acquired, err := w.CacheService.RedisClient.SetNX(context.Background(), checkLockKey, 1, checkLease).Result()
+			if err != nil {
+				log.Printf("Failed to acquire watchdog lock: %v", err)
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			if err := w.check(); err != nil {
+				log.Printf("Watchdog check failed: %v", err)
+				// Let the next run, on this or another replica, try again.
+				if err := w.CacheService.RedisClient.Del(context.Background(), checkLockKey).Err(); err != nil {
+					log.Printf("Failed to release watchdog lock: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func (w *Watchdog) check() error {
+	health, err := w.measure()
+	if err != nil {
+		return err
+	}
+
+	var previous IngestionStatusInfo
+	if _, err := w.CacheService.GetCachedData(ingestionStatusKey, &previous); err != nil {
+		return err
+	}
+	if previous.Stalled == health.Stalled {
+		return nil
+	}
+
+	if err := w.CacheService.CacheData(ingestionStatusKey, IngestionStatusInfo{Stalled: health.Stalled, Timestamp: time.Now()}, 0); err != nil {
+		return err
+	}
+
+	alertID, err := w.CacheService.IncrementCounter("alert_id")
+	if err != nil {
+		return err
+	}
+
+	alert := notifier.Alert{
+		ID:        alertID,
+		Kind:      notifier.AlertKindIngestionRecovered,
+		LastAlert: time.Now(),
+		Timestamp: uint32(time.Now().Unix()),
+		Ingestion: &health,
+	}
+	if health.Stalled {
+		alert.Kind = notifier.AlertKindIngestionStalled
+	} else {
+		alert.Duration = time.Since(previous.Timestamp)
+	}
+
+	log.Printf("Ingestion status changed: stalled=%t (last sample %s, last cycles fetch %s)",
+		health.Stalled, health.LastSampleAt.Format(time.RFC3339), health.LastCyclesFetchAt.Format(time.RFC3339))
+	return w.Notifier.PublishAlert(alert)
+}
+
+func (w *Watchdog) measure() (m.IngestionHealth, error) {
+	var health m.IngestionHealth
+
+	lastSample, err := w.ClickhouseService.GetLatestSampleTime()
+	if err != nil {
+		return health, err
+	}
+	health.LastSampleAt = lastSample
+
+	if _, err := w.CacheService.GetCachedData(services.LastCyclesFetchKey, &health.LastCyclesFetchAt); err != nil {
+		return health, err
+	}
+
+	threshold := lagThreshold()
+	health.LagThresholdSeconds = int64(threshold.Seconds())
+	health.Stalled = time.Since(health.LastSampleAt) > threshold || time.Since(health.LastCyclesFetchAt) > threshold
+
+	return health, nil
+}
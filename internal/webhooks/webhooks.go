@@ -0,0 +1,277 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	m "validators-health/internal/models"
+	"validators-health/internal/services"
+)
+
+// Events are signed with HMAC-SHA256 over "<timestamp>.<body>" using the
+// webhook's secret, so receivers can check both origin and freshness.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	pollInterval   = time.Second
+	claimBatch     = 50
+	requestTimeout = 10 * time.Second
+	maxAttempts    = 8
+	initialBackoff = 30 * time.Second
+	maxBackoff     = time.Hour
+	maxErrorLength = 512
+)
+
+// Dispatcher matches events against the registered webhooks and delivers
+// them with retries.
+type Dispatcher struct {
+	ClickhouseService *services.ClickhouseService
+	CacheService      *services.CacheService
+	client            *http.Client
+}
+
+func NewDispatcher(clickhouseService *services.ClickhouseService, cacheService *services.CacheService) *Dispatcher {
+	return &Dispatcher{
+		ClickhouseService: clickhouseService,
+		CacheService:      cacheService,
+		client:            newClient(),
+	}
+}
+
+// NewID returns a random hex identifier of n bytes.
+func NewID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Sign returns the signature header value for the body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues the event for every webhook whose filters match it.
+func (d *Dispatcher) Publish(event m.WebhookEvent) {
+	if event.ID == "" {
+		event.ID = NewID(16)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	webhooks, err := d.CacheService.ListWebhooks()
+	if err != nil {
+		log.Printf("Failed to get webhooks: %v", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !d.matches(webhook, event) {
+			continue
+		}
+		task := services.WebhookTask{WebhookID: webhook.ID, Event: event, Attempt: 1}
+		if err := d.CacheService.ScheduleWebhookTask(task, time.Now()); err != nil {
+			log.Printf("Failed to queue %s event for webhook %s: %v", event.Type, webhook.ID, err)
+		}
+	}
+}
+
+// matches applies the webhook's event type, ADNL and wallet filters.
+// Incidents concern the whole network, so only the type filter applies.
+func (d *Dispatcher) matches(webhook m.Webhook, event m.WebhookEvent) bool {
+	if len(webhook.Events) > 0 {
+		found := false
+		for _, eventType := range webhook.Events {
+			if eventType == event.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if event.Type == m.WebhookEventIncident || (len(webhook.ADNLs) == 0 && len(webhook.Wallets) == 0) {
+		return true
+	}
+
+	for _, adnl := range webhook.ADNLs {
+		if adnl == event.ADNLAddr || adnl == event.ValidatorADNL {
+			return true
+		}
+	}
+	for _, wallet := range webhook.Wallets {
+		group, err := d.ClickhouseService.GetWalletGroup(wallet, d.CacheService)
+		if err != nil {
+			log.Printf("Failed to get validators of wallet %s: %v", wallet, err)
+			continue
+		}
+		if group == nil {
+			continue
+		}
+		for _, member := range group.Members {
+			if member == event.ADNLAddr || member == event.ValidatorADNL {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Run delivers due events until stopped.
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			tasks, err := d.CacheService.ClaimWebhookTasks(claimBatch)
+			if err != nil {
+				log.Printf("Failed to claim webhook deliveries: %v", err)
+			}
+
+			var wg sync.WaitGroup
+			for _, task := range tasks {
+				wg.Add(1)
+				go func(task services.WebhookTask) {
+					defer wg.Done()
+					d.deliver(task)
+				}(task)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// deliver posts the event once, logs the attempt and schedules a retry if
+// it failed with a temporary error.
+func (d *Dispatcher) deliver(task services.WebhookTask) {
+	webhook, err := d.CacheService.GetWebhook(task.WebhookID)
+	if err != nil {
+		log.Printf("Failed to get webhook %s: %v", task.WebhookID, err)
+		d.retry(task)
+		return
+	}
+	if webhook == nil {
+		d.ack(task)
+		return
+	}
+
+	body, err := json.Marshal(task.Event)
+	if err != nil {
+		log.Printf("Failed to serialize event %s: %v", task.Event.ID, err)
+		d.ack(task)
+		return
+	}
+
+	delivery := m.WebhookDelivery{
+		Timestamp: time.Now(),
+		WebhookID: webhook.ID,
+		EventID:   task.Event.ID,
+		EventType: task.Event.Type,
+		Attempt:   task.Attempt,
+	}
+	statusCode, err := d.post(*webhook, task.Event, body)
+	delivery.DurationMs = time.Since(delivery.Timestamp).Milliseconds()
+	delivery.StatusCode = statusCode
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+		if len(delivery.Error) > maxErrorLength {
+			delivery.Error = delivery.Error[:maxErrorLength]
+		}
+	}
+
+	if err := d.ClickhouseService.InsertWebhookDelivery(delivery); err != nil {
+		log.Printf("Failed to log webhook delivery: %v", err)
+	}
+
+	if err != nil && retryable(statusCode) {
+		d.retry(task)
+		return
+	}
+	d.ack(task)
+}
+
+// ack removes the claimed task from the queue. Until then its lease keeps
+// it there, so a delivery interrupted by a restart is attempted again.
+func (d *Dispatcher) ack(task services.WebhookTask) {
+	if err := d.CacheService.AckWebhookTask(task); err != nil {
+		log.Printf("Failed to remove event %s for webhook %s from the queue: %v", task.Event.ID, task.WebhookID, err)
+	}
+}
+
+func (d *Dispatcher) post(webhook m.Webhook, event m.WebhookEvent, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, event.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed delivery is worth repeating. Network
+// errors have no status code.
+func retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500
+}
+
+func (d *Dispatcher) retry(task services.WebhookTask) {
+	if task.Attempt >= maxAttempts {
+		log.Printf("Giving up on event %s for webhook %s after %d attempts", task.Event.ID, task.WebhookID, task.Attempt)
+		d.ack(task)
+		return
+	}
+
+	backoff := initialBackoff << uint(task.Attempt-1)
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	next := task
+	next.Attempt++
+	if err := d.CacheService.ScheduleWebhookTask(next, time.Now().Add(backoff)); err != nil {
+		// The claimed task stays leased and comes up again.
+		log.Printf("Failed to schedule retry of event %s for webhook %s: %v", task.Event.ID, task.WebhookID, err)
+		return
+	}
+	d.ack(task)
+}
@@ -0,0 +1,22 @@
+package webhooks
+
+import "testing"
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	want := "sha256=086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54"
+
+	signature := Sign("secret", 1700000000, body)
+	if signature != want {
+		t.Errorf("Sign() = %s, want %s", signature, want)
+	}
+	for name, other := range map[string]string{
+		"secret":    Sign("other", 1700000000, body),
+		"timestamp": Sign("secret", 1700000001, body),
+		"body":      Sign("secret", 1700000000, []byte(`{"id":"2"}`)),
+	} {
+		if other == signature {
+			t.Errorf("signature doesn't depend on the %s", name)
+		}
+	}
+}
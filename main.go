@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,12 +13,15 @@ import (
 	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 	"validators-health/internal/clients/clickhouse"
 	"validators-health/internal/handlers"
 	"validators-health/internal/migrations"
 	"validators-health/internal/notifier"
 	"validators-health/internal/scrapper"
 	"validators-health/internal/services"
+	"validators-health/internal/watchdog"
+	"validators-health/internal/webhooks"
 )
 
 var (
@@ -37,17 +41,24 @@ func main() {
 
 	initServices()
 
+	n, err := notifier.NewNotifier(clickhouseService, cacheService)
+	if err != nil {
+		log.Fatalf("Failed to initialize Notifier: %v", err)
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
 	var wg sync.WaitGroup
-	wg.Add(3)
+	wg.Add(5)
 
 	stopChannel := make(chan struct{})
 
 	go runScrapper(&wg, stopChannel)
-	go runNotifier(&wg, stopChannel)
-	go runBackend(&wg, stopChannel)
+	go runNotifier(&wg, stopChannel, n)
+	go runBackend(&wg, stopChannel, n)
+	go runWatchdog(&wg, stopChannel, n)
+	go runWebhooks(&wg, stopChannel)
 
 	<-stop
 	log.Println("Shutting down gracefully...")
@@ -101,9 +112,14 @@ func runScrapper(wg *sync.WaitGroup, stop <-chan struct{}) {
 		}
 	} else {
 		for {
-			if err := s.ProcessCycles(stop, float64(effThreshold), nil, int(time.Now().Add(-60).Unix()), int(time.Now().Unix()), false); err != nil {
-				log.Fatalf("Scrapper failed: %v", err)
+			select {
+			case <-stop:
+				log.Println("Scrapper finished successfully.")
 				return
+			default:
+			}
+			if err := s.ProcessCycles(stop, float64(effThreshold), nil, 0, 0, false); err != nil {
+				log.Printf("Scrapper iteration failed: %v", err)
 			}
 		}
 	}
@@ -111,30 +127,50 @@ func runScrapper(wg *sync.WaitGroup, stop <-chan struct{}) {
 	log.Println("Scrapper finished successfully.")
 }
 
-func runNotifier(wg *sync.WaitGroup, stop <-chan struct{}) {
+func runNotifier(wg *sync.WaitGroup, stop <-chan struct{}, n *notifier.Notifier) {
 	defer wg.Done()
 	log.Println("Starting Notifier...")
-	n, err := notifier.NewNotifier(clickhouseService, cacheService)
-
-	if err != nil {
-		log.Fatalf("Failed to initialize Notifier: %v", err)
-	}
 	n.ListenAndNotify(stop)
 	log.Println("Notifier finished successfully.")
 }
 
-func runBackend(wg *sync.WaitGroup, stop <-chan struct{}) {
+func runWatchdog(wg *sync.WaitGroup, stop <-chan struct{}, n *notifier.Notifier) {
+	defer wg.Done()
+	log.Println("Starting Watchdog...")
+	w := watchdog.NewWatchdog(clickhouseService, cacheService, n)
+	w.Run(stop)
+	log.Println("Watchdog finished successfully.")
+}
+
+func runWebhooks(wg *sync.WaitGroup, stop <-chan struct{}) {
+	defer wg.Done()
+	log.Println("Starting webhook deliveries...")
+	d := webhooks.NewDispatcher(clickhouseService, cacheService)
+	d.Run(stop)
+	log.Println("Webhook deliveries finished successfully.")
+}
+
+func runBackend(wg *sync.WaitGroup, stop <-chan struct{}, n *notifier.Notifier) {
 	defer wg.Done()
 
 	h := handlers.NewHandlers(clickhouseService, cacheService)
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir("./static")))
+	h.Register(mux)
+	if n.UsesWebhook() {
+		mux.HandleFunc("POST "+notifier.WebhookPath, n.WebhookHandler)
+	}
+
+	// Requests inherit a context that is cancelled on shutdown, so
+	// long-lived streams end instead of holding Shutdown until its timeout.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 	server := &http.Server{
-		Addr:    ":3000",
-		Handler: nil,
+		Addr:        ":3000",
+		Handler:     handlers.JSONErrors(mux),
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
-	http.Handle("/", http.FileServer(http.Dir("./static")))
-	http.HandleFunc("/api/chart", h.ChartHandler)
-	http.HandleFunc("/api/health", h.HealthHandler)
-	http.HandleFunc("/api/validator-statuses", h.ValidatorStatusesHandler)
+	server.RegisterOnShutdown(cancelRequests)
 
 	serverErrChan := make(chan error, 1)
 	go func() {